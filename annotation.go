@@ -1,8 +1,9 @@
 package db
 
 import (
-	"log"
+	"log/slog"
 	"reflect"
+	"strings"
 )
 
 type Column struct {
@@ -16,13 +17,14 @@ type Column struct {
 	DefaultString   string
 	JoinString      string
 	TableName       string
+	Generate        string
 	SQLDefinition   string
 }
 
 func processTypeForColumns(t reflect.Type, columns map[string][]Column, key string) {
 	defer func() {
 		if err := recover(); err != nil {
-			log.Printf("panic at %s:%s", key, err)
+			slog.Error("read annotations", slog.String("key", key), slog.Any("panic", err))
 		}
 	}()
 	if t.Kind() == reflect.Struct {
@@ -53,7 +55,12 @@ func regularLogic(t reflect.Type, columns map[string][]Column, key string, idx i
 	defaultString, defaultOk := field.Tag.Lookup("default")
 	joinString, joinOk := field.Tag.Lookup("join")
 	tableNameString, tableNameOk := field.Tag.Lookup("tableName")
+	generateString, generateOk := field.Tag.Lookup("generate")
 
+	if !columnOk && field.Anonymous && field.Type.Kind() == reflect.Struct {
+		processTypeForColumns(field.Type, columns, key)
+		return columns
+	}
 	if !columnOk {
 		columns[key] = append(columns[key], Column{})
 		return columns
@@ -69,7 +76,7 @@ func regularLogic(t reflect.Type, columns map[string][]Column, key string, idx i
 	case reflect.String:
 		if datatypeOk {
 			if datatypeString == "uuid.UUID" {
-				column.TypeString = "varchar(35)"
+				column.TypeString = "varchar(36)"
 				if primaryKeyOk {
 					column.PrimaryKey = primaryKeyString
 				}
@@ -99,6 +106,13 @@ func regularLogic(t reflect.Type, columns map[string][]Column, key string, idx i
 		} else {
 			column.TypeString = "varchar(255)"
 		}
+		if generateOk {
+			column.Generate = generateString
+			column.TypeString = generatedType(generateString, column.TypeString)
+			if primaryKeyOk {
+				column.PrimaryKey = primaryKeyString
+			}
+		}
 		if nullStringOk && nullString == "true" {
 			column.NullString = "null"
 		} else if nullStringOk && nullString == "false" {
@@ -121,6 +135,15 @@ func regularLogic(t reflect.Type, columns map[string][]Column, key string, idx i
 		} else {
 			column.TypeString = "integer"
 		}
+		if generateOk {
+			column.Generate = generateString
+			if t, ok := autoIncrementType(field.Type.Kind()); ok && generateString == GenerateAutoIncrement {
+				column.TypeString = t
+			}
+		}
+		if primaryKeyOk {
+			column.PrimaryKey = primaryKeyString
+		}
 		if nullStringOk && nullString == "true" {
 			column.NullString = "null"
 		} else if nullStringOk && nullString == "false" {
@@ -160,6 +183,15 @@ func regularLogic(t reflect.Type, columns map[string][]Column, key string, idx i
 		} else {
 			column.TypeString = "integer"
 		}
+		if generateOk {
+			column.Generate = generateString
+			if t, ok := autoIncrementType(field.Type.Kind()); ok && generateString == GenerateAutoIncrement {
+				column.TypeString = t
+			}
+		}
+		if primaryKeyOk {
+			column.PrimaryKey = primaryKeyString
+		}
 		if nullStringOk && nullString == "true" {
 			column.NullString = "null"
 		} else if nullStringOk && nullString == "false" {
@@ -181,8 +213,19 @@ func regularLogic(t reflect.Type, columns map[string][]Column, key string, idx i
 				column.JoinString = joinString
 			}
 		}
+		if ptrField.Kind() == reflect.String {
+			column.TypeString = "varchar(255)"
+			if datatypeOk && datatypeString == "time.TIME" {
+				column.TypeString = "datetime"
+			} else if datatypeOk {
+				column.TypeString = datatypeString
+			}
+			if defaultOk {
+				column.DefaultString = defaultString
+			}
+		}
 		if ptrField.Kind() == reflect.Struct {
-			column.TypeString = "varchar(35)"
+			column.TypeString = foreignKeyType(field.Type)
 			if foreignKeyOk {
 				column.ForeignKey = foreignKeyString
 			}
@@ -194,7 +237,20 @@ func regularLogic(t reflect.Type, columns map[string][]Column, key string, idx i
 			}
 		}
 	case reflect.Struct:
-		processTypeForColumns(field.Type, columns, field.Name)
+		if !isRelationHandle(field.Type) {
+			processTypeForColumns(field.Type, columns, field.Name)
+			break
+		}
+		column.TypeString = foreignKeyType(field.Type)
+		if foreignKeyOk {
+			column.ForeignKey = foreignKeyString
+		}
+		if referencesOk {
+			column.ReferenceString = referencesString
+		}
+		if nullStringOk {
+			column.NullString = nullString
+		}
 	default:
 		columns[key] = append(columns[key], column)
 		return columns
@@ -219,7 +275,12 @@ func ptrLogic(t reflect.Type, columns map[string][]Column, key string, idx int)
 	defaultString, defaultOk := field.Tag.Lookup("default")
 	joinString, joinOk := field.Tag.Lookup("join")
 	tableNameString, tableNameOk := field.Tag.Lookup("tableName")
+	generateString, generateOk := field.Tag.Lookup("generate")
 
+	if !columnOk && field.Anonymous && field.Type.Kind() == reflect.Struct {
+		processTypeForColumns(field.Type, columns, key)
+		return columns
+	}
 	if !columnOk {
 		columns[key] = append(columns[key], Column{})
 		return columns
@@ -235,7 +296,7 @@ func ptrLogic(t reflect.Type, columns map[string][]Column, key string, idx int)
 	case reflect.String:
 		if datatypeOk {
 			if datatypeString == "uuid.UUID" {
-				column.TypeString = "varchar(35)"
+				column.TypeString = "varchar(36)"
 				if primaryKeyOk {
 					column.PrimaryKey = primaryKeyString
 				}
@@ -265,6 +326,13 @@ func ptrLogic(t reflect.Type, columns map[string][]Column, key string, idx int)
 		} else {
 			column.TypeString = "varchar(255)"
 		}
+		if generateOk {
+			column.Generate = generateString
+			column.TypeString = generatedType(generateString, column.TypeString)
+			if primaryKeyOk {
+				column.PrimaryKey = primaryKeyString
+			}
+		}
 		if nullStringOk && nullString == "true" {
 			column.NullString = "null"
 		} else if nullStringOk && nullString == "false" {
@@ -287,6 +355,15 @@ func ptrLogic(t reflect.Type, columns map[string][]Column, key string, idx int)
 		} else {
 			column.TypeString = "integer"
 		}
+		if generateOk {
+			column.Generate = generateString
+			if t, ok := autoIncrementType(field.Type.Kind()); ok && generateString == GenerateAutoIncrement {
+				column.TypeString = t
+			}
+		}
+		if primaryKeyOk {
+			column.PrimaryKey = primaryKeyString
+		}
 		if nullStringOk && nullString == "true" {
 			column.NullString = "null"
 		} else if nullStringOk && nullString == "false" {
@@ -326,6 +403,15 @@ func ptrLogic(t reflect.Type, columns map[string][]Column, key string, idx int)
 		} else {
 			column.TypeString = "integer"
 		}
+		if generateOk {
+			column.Generate = generateString
+			if t, ok := autoIncrementType(field.Type.Kind()); ok && generateString == GenerateAutoIncrement {
+				column.TypeString = t
+			}
+		}
+		if primaryKeyOk {
+			column.PrimaryKey = primaryKeyString
+		}
 		if nullStringOk && nullString == "true" {
 			column.NullString = "null"
 		} else if nullStringOk && nullString == "false" {
@@ -347,8 +433,32 @@ func ptrLogic(t reflect.Type, columns map[string][]Column, key string, idx int)
 				column.JoinString = joinString
 			}
 		}
+		if ptrField.Kind() == reflect.String {
+			column.TypeString = "varchar(255)"
+			if datatypeOk && datatypeString == "time.TIME" {
+				column.TypeString = "datetime"
+			} else if datatypeOk {
+				column.TypeString = datatypeString
+			}
+			if defaultOk {
+				column.DefaultString = defaultString
+			}
+		}
 		if ptrField.Kind() == reflect.Struct {
-			column.TypeString = "varchar(35)"
+			column.TypeString = foreignKeyType(field.Type)
+			if foreignKeyOk {
+				column.ForeignKey = foreignKeyString
+			}
+			if referencesOk {
+				column.ReferenceString = referencesString
+			}
+			if nullStringOk {
+				column.NullString = nullString
+			}
+		}
+	case reflect.Struct:
+		if isRelationHandle(field.Type) {
+			column.TypeString = foreignKeyType(field.Type)
 			if foreignKeyOk {
 				column.ForeignKey = foreignKeyString
 			}
@@ -366,3 +476,80 @@ func ptrLogic(t reflect.Type, columns map[string][]Column, key string, idx int)
 	columns[key] = append(columns[key], column)
 	return columns
 }
+
+func autoIncrementType(kind reflect.Kind) (string, bool) {
+	switch kind {
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return "bigint", true
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "int", true
+	}
+	return "", false
+}
+
+func generatedType(strategy string, typeString string) string {
+	switch strategy {
+	case GenerateUUIDv4:
+		fallthrough
+	case GenerateUUIDv7:
+		return "varchar(36)"
+	case GenerateULID:
+		return "char(26)"
+	}
+	return typeString
+}
+
+type fieldRef struct {
+	Field reflect.StructField
+	Value reflect.Value
+}
+
+func columnFields(ent interface{}) []fieldRef {
+	return appendColumnFields(make([]fieldRef, 0), reflect.Indirect(reflect.ValueOf(ent)))
+}
+
+func appendColumnFields(fields []fieldRef, v reflect.Value) []fieldRef {
+	if v.Kind() != reflect.Struct {
+		return fields
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, skip := field.Tag.Lookup("dbskip"); skip {
+			continue
+		}
+		if _, join := field.Tag.Lookup("join"); join {
+			continue
+		}
+		if rel, ok := field.Tag.Lookup("relation"); ok && rel != string(BelongsTo) {
+			continue
+		}
+		if field.Type.Kind() == reflect.Slice {
+			if _, ok := relationTargetType(field.Type); ok {
+				continue
+			}
+		}
+		if _, ok := field.Tag.Lookup("column"); ok {
+			fields = append(fields, fieldRef{Field: field, Value: v.Field(i)})
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = appendColumnFields(fields, v.Field(i))
+		}
+	}
+	return fields
+}
+
+func columnValue(ent interface{}, column string) (interface{}, bool) {
+	for _, f := range columnFields(ent) {
+		if strings.EqualFold(f.Field.Tag.Get("column"), column) {
+			return f.Value.Interface(), true
+		}
+	}
+	return nil, false
+}
+
+func tagEnabled(field reflect.StructField, tag string) bool {
+	v, ok := field.Tag.Lookup(tag)
+	return ok && v != "false"
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (c Repository) WithJoinValues(values ...KVP) Repository {
+	c.joinValues = append(append(make([]KVP, 0), c.joinValues...), values...)
+	return c
+}
+
+func (c Repository) joinPayload(rel Relation) ([]string, []interface{}) {
+	columns := make([]string, 0)
+	values := make([]interface{}, 0)
+	for _, kvp := range c.joinValues {
+		for _, jc := range rel.JoinColumns {
+			if strings.EqualFold(jc.Name, kvp.Key) {
+				columns = append(columns, jc.Name)
+				values = append(values, kvp.Value)
+			}
+		}
+	}
+	return columns, values
+}
+
+func (c Repository) association(parent Entity, name string) (Relation, interface{}, error) {
+	rel, err := c.Relation(parent, name)
+	if err != nil {
+		return rel, nil, err
+	}
+	if rel.Kind != ManyToMany {
+		return rel, nil, fmt.Errorf("%s: %s is a %s relation, not %s", parent.GetTable(), name, rel.Kind, ManyToMany)
+	}
+	for _, kvp := range c.joinValues {
+		found := false
+		for _, jc := range rel.JoinColumns {
+			found = found || strings.EqualFold(jc.Name, kvp.Key)
+		}
+		if !found {
+			return rel, nil, fmt.Errorf("%s: no join column %s", rel.JoinTable, kvp.Key)
+		}
+	}
+	key := relationKey(parent, primaryKeyColumns(parent)[0])
+	if key == nil || keyString(key) == "" {
+		return rel, nil, fmt.Errorf("%s: %s has no id", rel.JoinTable, parent.GetTable())
+	}
+	return rel, key, nil
+}
+
+func associationKeys(rel Relation, children []Entity) ([]interface{}, error) {
+	keys := make([]interface{}, 0)
+	for _, child := range children {
+		key := relationKey(child, rel.References)
+		if key == nil || keyString(key) == "" {
+			return nil, fmt.Errorf("%s: %s has no id, save it first", rel.JoinTable, child.GetTable())
+		}
+		keys = append(keys, key)
+	}
+	return uniqueKeys(keys), nil
+}
+
+func (c Repository) Associate(parent Entity, name string, children ...Entity) error {
+	rel, key, err := c.association(parent, name)
+	if err != nil {
+		return err
+	}
+	keys, err := associationKeys(rel, children)
+	if err != nil {
+		return err
+	}
+	payloadColumns, payload := c.joinPayload(rel)
+	rows := make([][]interface{}, 0)
+	for _, k := range keys {
+		rows = append(rows, append([]interface{}{key, k}, payload...))
+	}
+	return c.linkRows(rel.JoinTable, append([]string{rel.JoinForeignKey, rel.JoinReferences}, payloadColumns...), rows)
+}
+
+func (c Repository) Dissociate(parent Entity, name string, children ...Entity) error {
+	rel, key, err := c.association(parent, name)
+	if err != nil {
+		return err
+	}
+	keys, err := associationKeys(rel, children)
+	if err != nil {
+		return err
+	}
+	return c.unlinkRows(rel, key, keys, false)
+}
+
+func (c Repository) ReplaceAssociations(parent Entity, name string, children ...Entity) error {
+	rel, key, err := c.association(parent, name)
+	if err != nil {
+		return err
+	}
+	keys, err := associationKeys(rel, children)
+	if err != nil {
+		return err
+	}
+	return c.WithTx(c.context(), func(tx Repository) error {
+		if err := tx.unlinkRows(rel, key, keys, true); err != nil {
+			return err
+		}
+		return tx.Associate(parent, name, children...)
+	})
+}
+
+func (c Repository) CountAssociations(parent Entity, name string) (int64, error) {
+	rel, key, err := c.association(parent, name)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	rows, err := c.query(tableStmt(rel.JoinTable, OpCount, "SELECT COUNT(*) FROM "+rel.JoinTable+" WHERE "+rel.JoinForeignKey+" = ?", key))
+	if err != nil {
+		return 0, sqlError(rel.JoinTable, "COUNT", "", err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		err = rows.Scan(&count)
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		return 0, sqlError(rel.JoinTable, "COUNT", "", err)
+	}
+	return count, nil
+}
+
+func (c Repository) unlinkRows(rel Relation, key interface{}, keys []interface{}, keep bool) error {
+	q := "DELETE FROM " + rel.JoinTable + " WHERE " + rel.JoinForeignKey + " = ?"
+	if !keep && len(keys) == 0 {
+		return nil
+	}
+	if keep && len(keys) == 0 {
+		if _, err := c.exec(tableStmt(rel.JoinTable, OpDelete, q, key)); err != nil {
+			return sqlError(rel.JoinTable, "DELETE", "", err)
+		}
+		return nil
+	}
+	op := " IN "
+	if keep {
+		op = " NOT IN "
+	}
+	args := append([]interface{}{key}, keys...)
+	if _, err := c.exec(tableStmt(rel.JoinTable, OpDelete, q+" AND "+rel.JoinReferences+op+"("+strings.Join(placeholderList(len(keys)), ",")+")", args...)); err != nil {
+		return sqlError(rel.JoinTable, "DELETE", "", err)
+	}
+	return nil
+}
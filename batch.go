@@ -0,0 +1,294 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const maxPlaceholdersMySQL int = 65535
+const maxPlaceholdersPostgres int = 65535
+const maxPlaceholdersSQLite int = 32766
+
+type ChunkError struct {
+	Table  string
+	Offset int
+	Count  int
+	Err    error
+}
+
+func (e ChunkError) Error() string {
+	return fmt.Sprintf("%s rows %d-%d: %v", e.Table, e.Offset, e.Offset+e.Count-1, e.Err)
+}
+
+func (e ChunkError) Unwrap() error {
+	return e.Err
+}
+
+type BatchError struct {
+	Chunks []ChunkError
+}
+
+func (e *BatchError) Error() string {
+	s := make([]string, 0)
+	for _, c := range e.Chunks {
+		s = append(s, c.Error())
+	}
+	return fmt.Sprintf("%d chunk(s) failed: %s", len(e.Chunks), strings.Join(s, "; "))
+}
+
+func (e *BatchError) Unwrap() []error {
+	results := make([]error, 0)
+	for _, c := range e.Chunks {
+		results = append(results, c)
+	}
+	return results
+}
+
+func (e *BatchError) add(table string, offset int, count int, err error) {
+	var be *BatchError
+	if errors.As(err, &be) {
+		e.Chunks = append(e.Chunks, be.Chunks...)
+		return
+	}
+	e.Chunks = append(e.Chunks, ChunkError{Table: table, Offset: offset, Count: count, Err: err})
+}
+
+func (e *BatchError) orNil() error {
+	if len(e.Chunks) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (c Repository) WithBatchSize(n int) Repository {
+	c.batchSize = n
+	return c
+}
+
+func (c Repository) chunkSize(columns int) int {
+	limit := maxPlaceholdersMySQL
+	switch c.DB.GetDialect() {
+	case DialectPostgres:
+		limit = maxPlaceholdersPostgres
+	case DialectSQLite:
+		limit = maxPlaceholdersSQLite
+	}
+	size := limit
+	if columns > 0 {
+		size = limit / columns
+	}
+	if c.batchSize > 0 && c.batchSize < size {
+		size = c.batchSize
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+func (c Repository) saveBatch(ents []Entity, batchErr *BatchError) {
+	table := ents[0].GetTable()
+	for _, e := range ents {
+		c.keep(e)
+		if _, err := c.touchTimestamps(e, true); err != nil {
+			batchErr.add(table, 0, len(ents), err)
+			return
+		}
+		if err := generateIDs(e); err != nil {
+			batchErr.add(table, 0, len(ents), err)
+			return
+		}
+	}
+	if !autoIDsBatchable(ents) {
+		for i, e := range ents {
+			fields, auto := insertFields(e)
+			q := insertQuery(strings.Join(columnNames(fields), ","), strings.Join(placeholderList(len(fields)), ","), table)
+			if err := c.insertRow(e, "SAVE", q+c.upsertClause(e, fields), fields, auto, false); err != nil {
+				batchErr.add(table, i, 1, err)
+			}
+		}
+		return
+	}
+	fields, auto := insertFields(ents[0])
+	columns := columnNames(fields)
+	row := "(" + strings.Join(placeholderList(len(columns)), ",") + ")"
+	size := c.chunkSize(len(columns))
+	for offset := 0; offset < len(ents); offset += size {
+		end := offset + size
+		if end > len(ents) {
+			end = len(ents)
+		}
+		rows := make([]string, 0)
+		values := make([]interface{}, 0)
+		for _, e := range ents[offset:end] {
+			rows = append(rows, row)
+			if auto != nil {
+				fields, _ := insertFields(e)
+				values = append(values, columnValues(fields)...)
+				continue
+			}
+			values = append(values, GetValues(e)...)
+		}
+		q := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(columns, ","), strings.Join(rows, ","))
+		q += c.upsertClause(ents[0], fields)
+		var err error
+		if auto == nil {
+			_, err = c.exec(stmt(ents[0], OpSave, q, values...))
+		} else {
+			err = c.insertBatchIDs(ents[offset:end], q, values)
+		}
+		c.invalidate(table)
+		if err = handleSQLError(nil, ents[0], "SAVE", err, ""); err != nil {
+			batchErr.add(table, offset, end-offset, err)
+		}
+	}
+}
+
+func autoIDsBatchable(ents []Entity) bool {
+	for _, e := range ents {
+		if _, auto := insertFields(e); auto != nil && !auto.Value.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Repository) insertBatchIDs(ents []Entity, q string, values []interface{}) error {
+	_, auto := insertFields(ents[0])
+	column := auto.Field.Tag.Get("column")
+	if c.DB.GetDialect() != DialectMySQL {
+		rows, err := c.query(stmt(ents[0], OpSave, q+" RETURNING "+column, values...))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		n := 0
+		for ; rows.Next() && n < len(ents); n++ {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			_, auto := insertFields(ents[n])
+			if err := setAutoID(auto, id); err != nil {
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n != len(ents) {
+			return fmt.Errorf("returned %d %s values for %d rows", n, column, len(ents))
+		}
+		return nil
+	}
+	res, err := c.exec(stmt(ents[0], OpSave, q, values...))
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != int64(len(ents)) {
+		return fmt.Errorf("cannot assign %s: %d rows affected for %d rows", column, affected, len(ents))
+	}
+	first, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for i, e := range ents {
+		_, auto := insertFields(e)
+		if err := setAutoID(auto, first+int64(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c Repository) saveRelated(parents []Entity) error {
+	children := make([]Entity, 0)
+	joins := make([]Entity, 0)
+	seen := make(map[Entity]bool)
+	addChild := func(e Entity) {
+		if reflect.TypeOf(e).Kind() == reflect.Ptr {
+			if seen[e] {
+				return
+			}
+			seen[e] = true
+		}
+		children = append(children, e)
+	}
+	type link struct {
+		rel    Relation
+		parent Entity
+		child  Entity
+	}
+	links := make([]link, 0)
+	for _, p := range parents {
+		rels, err := c.Relations(p)
+		if err != nil {
+			return err
+		}
+		for _, rel := range rels {
+			switch rel.Kind {
+			case HasOne, HasMany:
+				key := relationKey(p, rel.References)
+				for _, t := range rel.targets(p) {
+					setColumn(t, rel.ForeignKey, key)
+					addChild(t)
+				}
+			case ManyToMany:
+				for _, t := range rel.targets(p) {
+					addChild(t)
+					links = append(links, link{rel: rel, parent: p, child: t})
+				}
+			}
+		}
+		kids, err := p.GetChildren()
+		if err != nil {
+			return err
+		}
+		for _, k := range kids {
+			addChild(k)
+			join, err := p.GetJoin(k)
+			if err != nil {
+				return err
+			}
+			if j, ok := join.(Entity); ok {
+				joins = append(joins, j)
+			}
+		}
+	}
+	batchErr := &BatchError{}
+	if len(children) > 0 {
+		if err := c.SaveAll(children); err != nil {
+			batchErr.add(children[0].GetTable(), 0, len(children), err)
+		}
+	}
+	if len(joins) > 0 {
+		if err := c.SaveAll(joins); err != nil {
+			batchErr.add(joins[0].GetTable(), 0, len(joins), err)
+		}
+	}
+	tables := make([]string, 0)
+	rows := make(map[string][][]interface{})
+	columns := make(map[string][]string)
+	for _, l := range links {
+		table := l.rel.JoinTable
+		payloadColumns, payload := c.joinPayload(l.rel)
+		if _, ok := rows[table]; !ok {
+			tables = append(tables, table)
+			columns[table] = append([]string{l.rel.JoinForeignKey, l.rel.JoinReferences}, payloadColumns...)
+		}
+		row := []interface{}{relationKey(l.parent, primaryKeyColumns(l.parent)[0]), relationKey(l.child, l.rel.References)}
+		rows[table] = append(rows[table], append(row, payload...))
+	}
+	for _, table := range tables {
+		if err := c.linkRows(table, columns[table], rows[table]); err != nil {
+			batchErr.add(table, 0, len(rows[table]), err)
+		}
+	}
+	return batchErr.orNil()
+}
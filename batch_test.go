@@ -0,0 +1,94 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSaveAllChunksMultiRowInserts(t *testing.T) {
+	d, db := newFakeDB(t)
+	ents := make([]Entity, 0)
+	for i := 0; i < 5; i++ {
+		ents = append(ents, &widget{Name: "w"})
+	}
+	if err := (Repository{DB: db}).WithBatchSize(2).SaveAll(ents); err != nil {
+		t.Fatal(err)
+	}
+	got := d.statements()
+	if len(got) != 3 {
+		t.Fatalf("ran %d statements: %v", len(got), got)
+	}
+	for i, rows := range []int{2, 2, 1} {
+		if n := strings.Count(strings.SplitN(got[i], " ON DUPLICATE", 2)[0], "(?,?,?,?,?,?)"); n != rows {
+			t.Fatalf("chunk %d has %d rows: %s", i, n, got[i])
+		}
+	}
+}
+
+func TestSaveAllReportsFailedChunks(t *testing.T) {
+	d, db := newFakeDB(t)
+	failure := errors.New("boom")
+	d.failOn("INSERT INTO widget", failure, 1)
+	ents := []Entity{&widget{Name: "a"}, &widget{Name: "b"}, &widget{Name: "c"}}
+	err := (Repository{DB: db}).WithBatchSize(2).SaveAll(ents)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Chunks) != 1 {
+		t.Fatalf("err = %v", err)
+	}
+	chunk := batchErr.Chunks[0]
+	if chunk.Table != "widget" || chunk.Offset != 0 || chunk.Count != 2 || !errors.Is(err, failure) {
+		t.Fatalf("chunk = %+v", chunk)
+	}
+}
+
+func TestChunkSizeFollowsPlaceholderLimit(t *testing.T) {
+	db := &DB{}
+	db.SetDialect(DialectSQLite)
+	if got := (Repository{DB: db}).chunkSize(6); got != maxPlaceholdersSQLite/6 {
+		t.Fatalf("chunk size = %d", got)
+	}
+}
+
+func TestSaveAllBatchesAutoIncrementKeys(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.lastID = 10
+	d.affected = 3
+	ents := []Entity{&account{}, &account{}, &account{}}
+	if err := (Repository{DB: db}).SaveAll(ents); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.count("INSERT INTO account"); n != 1 {
+		t.Fatalf("ran %d inserts: %v", n, d.statements())
+	}
+	for i, e := range ents {
+		if key := e.(*account).Key; key != int64(10+i) {
+			t.Fatalf("row %d got key %d", i, key)
+		}
+	}
+}
+
+func TestSaveAllReadsBackAutoIncrementKeys(t *testing.T) {
+	d, db := newFakeDB(t)
+	db.SetDialect(DialectSQLite)
+	d.returns("RETURNING key", []string{"key"}, []driver.Value{int64(7)}, []driver.Value{int64(8)})
+	ents := []Entity{&account{}, &account{}}
+	if err := (Repository{DB: db}).SaveAll(ents); err != nil {
+		t.Fatal(err)
+	}
+	if d.count("INSERT INTO account") != 1 || ents[0].(*account).Key != 7 || ents[1].(*account).Key != 8 {
+		t.Fatalf("keys %d, %d after %v", ents[0].(*account).Key, ents[1].(*account).Key, d.statements())
+	}
+}
+
+func TestSaveAllInsertsRowsWithKeysOneByOne(t *testing.T) {
+	d, db := newFakeDB(t)
+	ents := []Entity{&account{Key: 3}, &account{}}
+	if err := (Repository{DB: db}).SaveAll(ents); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.count("INSERT INTO account"); n != 2 {
+		t.Fatalf("ran %d inserts: %v", n, d.statements())
+	}
+}
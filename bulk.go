@@ -0,0 +1,248 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var bulkSeq atomic.Int64
+
+func (c Repository) BulkLoad(ctx context.Context, ents iter.Seq[Entity]) (int64, error) {
+	c.ctx = ctx
+	next, stop := iter.Pull(ents)
+	defer stop()
+	first, ok := next()
+	if !ok {
+		return 0, nil
+	}
+	defer c.invalidate(first.GetTable())
+	pending := func(yield func(Entity) bool) {
+		if !yield(first) {
+			return
+		}
+		for {
+			e, ok := next()
+			if !ok || !yield(e) {
+				return
+			}
+		}
+	}
+	switch c.DB.GetDialect() {
+	case DialectMySQL:
+		return c.loadData(first, pending)
+	case DialectPostgres:
+		return c.copyFrom(first, pending)
+	}
+	return c.loadBatches(first, pending)
+}
+
+func (c Repository) prepareBulk(first Entity, e Entity) error {
+	if e.GetTable() != first.GetTable() {
+		return fmt.Errorf("bulk load %s: got %s entity", first.GetTable(), e.GetTable())
+	}
+	c.keep(e)
+	if _, err := c.touchTimestamps(e, true); err != nil {
+		return err
+	}
+	return generateIDs(e)
+}
+
+func (c Repository) loadData(first Entity, ents iter.Seq[Entity]) (int64, error) {
+	pr, pw := io.Pipe()
+	name := fmt.Sprintf("go-db-bulk-%d", bulkSeq.Add(1))
+	mysql.RegisterReaderHandler(name, func() io.Reader { return pr })
+	defer mysql.DeregisterReaderHandler(name)
+
+	var writeErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writeErr = c.writeTSV(pw, first, ents)
+		pw.CloseWithError(writeErr)
+	}()
+
+	q := fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s CHARACTER SET utf8mb4 FIELDS TERMINATED BY '\\t' ESCAPED BY '\\\\' LINES TERMINATED BY '\\n' (%s)",
+		name, first.GetTable(), strings.Join(GetColumns(first), ","))
+	res, err := c.exec(stmt(first, OpLoad, q))
+	pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+	if writeErr != nil && writeErr != io.ErrClosedPipe {
+		return 0, writeErr
+	}
+	if err = handleSQLError(nil, first, "LOAD", err, ""); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (c Repository) writeTSV(out io.Writer, first Entity, ents iter.Seq[Entity]) error {
+	w := bufio.NewWriter(out)
+	for e := range ents {
+		if err := c.prepareBulk(first, e); err != nil {
+			return err
+		}
+		for i, v := range GetValues(e) {
+			if i > 0 {
+				if err := w.WriteByte('\t'); err != nil {
+					return err
+				}
+			}
+			s, err := tsvValue(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", e.GetTable(), err)
+			}
+			if _, err := w.WriteString(s); err != nil {
+				return err
+			}
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (c Repository) copyFrom(first Entity, ents iter.Seq[Entity]) (int64, error) {
+	if pkg := driverPackage(c.DB.Conn.Driver()); !strings.HasPrefix(pkg, "github.com/lib/pq") {
+		return 0, fmt.Errorf("bulk load %s: COPY FROM STDIN needs the github.com/lib/pq driver, got %T", first.GetTable(), c.DB.Conn.Driver())
+	}
+	var n int64
+	c.retry = nil
+	err := c.WithTx(c.context(), func(r Repository) error {
+		q := copyInQuery(DialectPostgres, first.GetTable(), GetColumns(first))
+		prepared, err := r.tx.PrepareContext(r.context(), q)
+		if err != nil {
+			return handleSQLError(nil, first, "COPY", err, "")
+		}
+		defer prepared.Close()
+		conn := preparedConn{prepared}
+		for e := range ents {
+			if err := r.prepareBulk(first, e); err != nil {
+				return err
+			}
+			if err := r.DB.run(r.context(), conn, stmt(e, OpLoad, q, GetValues(e)...)); err != nil {
+				return handleSQLError(nil, e, "COPY", err, "")
+			}
+			n++
+		}
+		err = r.DB.run(r.context(), conn, stmt(first, OpLoad, q))
+		return handleSQLError(nil, first, "COPY", err, "")
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (c Repository) loadBatches(first Entity, ents iter.Seq[Entity]) (int64, error) {
+	var n int64
+	size := c.chunkSize(len(GetColumns(first)))
+	batch := make([]Entity, 0)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		batchErr := &BatchError{}
+		c.saveBatch(batch, batchErr)
+		if err := batchErr.orNil(); err != nil {
+			return err
+		}
+		n += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+	for e := range ents {
+		if e.GetTable() != first.GetTable() {
+			return n, fmt.Errorf("bulk load %s: got %s entity", first.GetTable(), e.GetTable())
+		}
+		batch = append(batch, e)
+		if len(batch) >= size {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, flush()
+}
+
+func driverPackage(d driver.Driver) string {
+	t := reflect.TypeOf(d)
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.PkgPath()
+}
+
+func copyInQuery(dialect string, table string, columns []string) string {
+	quoted := make([]string, 0)
+	for _, col := range columns {
+		quoted = append(quoted, quoteIdentifier(dialect, col))
+	}
+	return fmt.Sprintf("COPY %s (%s) FROM STDIN", quoteIdentifier(dialect, table), strings.Join(quoted, ", "))
+}
+
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r", "\x00", "\\0")
+
+func tsvValue(v interface{}) (string, error) {
+	if r, ok := v.(Redacted); ok {
+		v = r.value
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		v = dv
+	}
+	dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		return "", err
+	}
+	switch t := dv.(type) {
+	case nil:
+		return "\\N", nil
+	case string:
+		return tsvEscaper.Replace(t), nil
+	case []byte:
+		return tsvEscaper.Replace(string(t)), nil
+	case bool:
+		if t {
+			return "1", nil
+		}
+		return "0", nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case time.Time:
+		return t.Format("2006-01-02 15:04:05.999999"), nil
+	}
+	return "", fmt.Errorf("unsupported bulk value %T", dv)
+}
+
+type preparedConn struct {
+	stmt *sql.Stmt
+}
+
+func (p preparedConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.stmt.QueryContext(ctx, args...)
+}
+
+func (p preparedConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.stmt.ExecContext(ctx, args...)
+}
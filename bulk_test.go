@@ -0,0 +1,87 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"iter"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type gadget struct {
+	widget
+}
+
+func (g *gadget) GetTable() string {
+	return "gadget"
+}
+
+func widgets(n int, finished *atomic.Bool) iter.Seq[Entity] {
+	return func(yield func(Entity) bool) {
+		defer finished.Store(true)
+		for i := 0; i < n; i++ {
+			if !yield(&widget{Name: strings.Repeat("x", 64)}) {
+				return
+			}
+		}
+	}
+}
+
+func TestLoadDataWaitsForWriter(t *testing.T) {
+	d, db := newFakeDB(t)
+	var finished atomic.Bool
+	if _, err := (Repository{DB: db}).BulkLoad(context.Background(), widgets(10000, &finished)); err != nil {
+		t.Fatal(err)
+	}
+	if !finished.Load() {
+		t.Fatal("BulkLoad returned while the writer was still reading entities")
+	}
+	if got := d.statements()[0]; !strings.HasPrefix(got, "LOAD DATA LOCAL INFILE 'Reader::go-db-bulk-") {
+		t.Fatalf("ran %s", got)
+	}
+}
+
+func TestLoadDataReportsWriterErrors(t *testing.T) {
+	_, db := newFakeDB(t)
+	ents := func(yield func(Entity) bool) {
+		if yield(&widget{}) {
+			yield(&gadget{})
+		}
+	}
+	_, err := (Repository{DB: db}).BulkLoad(context.Background(), ents)
+	if err == nil || !strings.Contains(err.Error(), "bulk load widget: got gadget entity") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestWriteTSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := &widget{Name: "a\tb\\c"}
+	w.ID = "w1"
+	r := Repository{Clock: fixedClock}
+	if err := r.writeTSV(&buf, w, func(yield func(Entity) bool) { yield(w) }); err != nil {
+		t.Fatal(err)
+	}
+	want := "w1\t2024-01-02 03:04:05\t2024-01-02 03:04:05\t\\N\ta\\tb\\\\c\t0\n"
+	if buf.String() != want {
+		t.Fatalf("tsv = %q", buf.String())
+	}
+}
+
+func TestCopyFromNeedsLibPQ(t *testing.T) {
+	_, db := newFakeDB(t)
+	db.SetDialect(DialectPostgres)
+	var finished atomic.Bool
+	_, err := (Repository{DB: db}).BulkLoad(context.Background(), widgets(1, &finished))
+	if err == nil || !strings.Contains(err.Error(), "github.com/lib/pq") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestCopyInQueryQuotesIdentifiers(t *testing.T) {
+	got := copyInQuery(DialectPostgres, `odd"table`, []string{"id", `na"me`})
+	if got != `COPY "odd""table" ("id", "na""me") FROM STDIN` {
+		t.Fatalf("query = %s", got)
+	}
+}
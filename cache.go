@@ -0,0 +1,265 @@
+package db
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	items   map[string]*list.Element
+	recency *list.List
+	now     func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:    size,
+		items:   make(map[string]*list.Element),
+		recency: list.New(),
+		now:     time.Now,
+	}
+}
+
+func (l *LRUCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*lruEntry)
+	if !entry.expires.IsZero() && !l.now().Before(entry.expires) {
+		l.recency.Remove(el)
+		delete(l.items, key)
+		return nil, false, nil
+	}
+	l.recency.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+func (l *LRUCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := &lruEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expires = l.now().Add(ttl)
+	}
+	if el, ok := l.items[key]; ok {
+		el.Value = entry
+		l.recency.MoveToFront(el)
+		return nil
+	}
+	l.items[key] = l.recency.PushFront(entry)
+	for l.size > 0 && l.recency.Len() > l.size {
+		oldest := l.recency.Back()
+		l.recency.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+func (l *LRUCache) DeletePrefix(ctx context.Context, prefix string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, el := range l.items {
+		if strings.HasPrefix(key, prefix) {
+			l.recency.Remove(el)
+			delete(l.items, key)
+		}
+	}
+	return nil
+}
+
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.recency.Len()
+}
+
+func (c Repository) NoCache() Repository {
+	c.noCache = true
+	return c
+}
+
+func cacheTTL(ent Entity) (time.Duration, bool) {
+	return findCacheTTL(entityType(ent))
+}
+
+func findCacheTTL(t reflect.Type) (time.Duration, bool) {
+	if t.Kind() != reflect.Struct {
+		return 0, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if v, ok := field.Tag.Lookup("cacheTTL"); ok {
+			ttl, err := time.ParseDuration(v)
+			return ttl, err == nil
+		}
+		if field.Anonymous {
+			if ttl, ok := findCacheTTL(field.Type); ok {
+				return ttl, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func cachePrefix(table string) string {
+	return "db:" + table + ":"
+}
+
+func (c Repository) cacheKey(ent Entity, op string, ids []string) (string, time.Duration, bool) {
+	paginated := c.pagination.size > 0 || len(c.pagination.order) > 0 || c.pagination.after != "" || c.pagination.before != ""
+	if c.DB.GetCache() == nil || c.noCache || c.tx != nil || c.lock != LockNone || len(c.joins) > 0 || paginated {
+		return "", 0, false
+	}
+	ttl, ok := cacheTTL(ent)
+	if !ok {
+		return "", 0, false
+	}
+	return fmt.Sprintf("%s%s:%d:%s", cachePrefix(ent.GetTable()), op, c.deleted, strings.Join(ids, ",")), ttl, true
+}
+
+func (c Repository) cacheGet(proto Entity, key string) ([]Entity, bool) {
+	rows, ok := c.cacheRows(key)
+	if !ok {
+		return nil, false
+	}
+	results := make([]Entity, 0)
+	for _, row := range rows {
+		e := newEntity(proto)
+		if err := c.fromCache(e, row); err != nil {
+			return nil, false
+		}
+		results = append(results, c.remember(e))
+	}
+	return results, true
+}
+
+func (c Repository) cacheRows(key string) ([]map[string]interface{}, bool) {
+	data, ok, err := c.DB.GetCache().Get(c.context(), key)
+	if err != nil || !ok {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	rows := make([]map[string]interface{}, 0)
+	if err := dec.Decode(&rows); err != nil {
+		return nil, false
+	}
+	for _, row := range rows {
+		for column, v := range row {
+			if n, ok := v.(json.Number); ok {
+				if iv, err := n.Int64(); err == nil {
+					row[column] = iv
+				} else if fv, err := n.Float64(); err == nil {
+					row[column] = fv
+				}
+			}
+		}
+	}
+	return rows, true
+}
+
+func (c Repository) fromCache(e Entity, row map[string]interface{}) error {
+	if err := assignColumns(e, row); err != nil {
+		return err
+	}
+	if err := c.bindHandles(e); err != nil {
+		return err
+	}
+	return c.afterLoad(e)
+}
+
+func cacheRow(ent Entity) (map[string]interface{}, error) {
+	row := make(map[string]interface{})
+	for _, f := range columnFields(ent) {
+		if !f.Field.IsExported() {
+			continue
+		}
+		v := snapshotValue(fieldValue(f))
+		switch t := v.(type) {
+		case time.Time:
+			v = t.Format(time.RFC3339Nano)
+		case []byte:
+			if !utf8.Valid(t) {
+				return nil, fmt.Errorf("%s: cannot cache binary column %s", ent.GetTable(), f.Field.Tag.Get("column"))
+			}
+			v = string(t)
+		}
+		row[strings.ToLower(f.Field.Tag.Get("column"))] = v
+	}
+	return row, nil
+}
+
+func (c Repository) cacheSet(key string, ttl time.Duration, ents []Entity) {
+	rows := make([]map[string]interface{}, 0)
+	for _, e := range ents {
+		row, err := cacheRow(e)
+		if err != nil {
+			return
+		}
+		rows = append(rows, row)
+	}
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return
+	}
+	c.DB.GetCache().Set(c.context(), key, data, ttl)
+}
+
+func (c Repository) invalidate(tables ...string) {
+	if c.DB.GetCache() == nil {
+		return
+	}
+	for _, table := range tables {
+		c.DB.GetCache().DeletePrefix(c.context(), cachePrefix(table))
+		if c.written != nil {
+			c.written.add(table)
+		}
+	}
+}
+
+type writtenTables struct {
+	mu     sync.Mutex
+	tables []string
+}
+
+func (w *writtenTables) add(table string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, t := range w.tables {
+		if t == table {
+			return
+		}
+	}
+	w.tables = append(w.tables, table)
+}
+
+func (w *writtenTables) list() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append(make([]string, 0), w.tables...)
+}
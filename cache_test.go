@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+type note struct {
+	widget
+	Secret string `json:"-" column:"secret" cacheTTL:"1m"`
+}
+
+func (n *note) GetTable() string {
+	return "note"
+}
+
+func (n *note) ScanLocal(rows *sql.Rows, e Entity) error {
+	return scanByColumn(rows, e)
+}
+
+func (n note) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+func cachedNotes(t *testing.T) (*fakeDriver, *DB) {
+	d, db := newFakeDB(t)
+	db.SetCache(NewLRUCache(10))
+	d.returns("FROM note", append(append(make([]string, 0), widgetColumns...), "secret"), append(widgetRow("n1", "a", 3), driver.Value("s3cret")))
+	return d, db
+}
+
+func TestCacheUsesColumnTags(t *testing.T) {
+	d, db := cachedNotes(t)
+	repo := Repository{DB: db}
+	if err := repo.Take(&note{}, "n1"); err != nil {
+		t.Fatal(err)
+	}
+	n := &note{}
+	if err := repo.Take(n, "n1"); err != nil {
+		t.Fatal(err)
+	}
+	if d.count("SELECT") != 1 {
+		t.Fatalf("second Take was not served from the cache: %q", d.statements())
+	}
+	if n.ID != "n1" || n.Secret != "s3cret" || n.Version != 3 || n.Created == "" {
+		t.Fatalf("cached entity lost columns: %+v", n)
+	}
+}
+
+func TestCachedTakeIsCanonicalInSession(t *testing.T) {
+	_, db := cachedNotes(t)
+	if err := (Repository{DB: db}).Take(&note{}, "n1"); err != nil {
+		t.Fatal(err)
+	}
+	s := Repository{DB: db}.Session()
+	n := &note{}
+	if err := s.Take(n, "n1"); err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := SessionGet[*note](s, "n1")
+	if err != nil || mapped != n {
+		t.Fatalf("expected the Take argument to be mapped, got %p and %p (%v)", mapped, n, err)
+	}
+}
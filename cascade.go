@@ -0,0 +1,182 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	CascadeDelete     string = "delete"
+	CascadeDetach     string = "detach"
+	CascadeUnlink     string = "unlink"
+	CascadeSoftDelete string = "softDelete"
+)
+
+type DeleteStep struct {
+	Table  string
+	Action string
+	Query  string
+	Args   []interface{}
+	ent    Entity
+}
+
+func (s DeleteStep) String() string {
+	return fmt.Sprintf("%s %s: %s %v", s.Action, s.Table, s.Query, s.Args)
+}
+
+func (c Repository) PlanDelete(e Entity) ([]DeleteStep, error) {
+	return c.planDelete(e, make(map[string]bool), false)
+}
+
+func (c Repository) planDelete(e Entity, seen map[string]bool, soft bool) ([]DeleteStep, error) {
+	id, err := e.GetID()
+	if err != nil {
+		return nil, err
+	}
+	if seen[e.GetTable()+":"+id] {
+		return nil, nil
+	}
+	seen[e.GetTable()+":"+id] = true
+	rels, err := c.Relations(e)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]DeleteStep, 0)
+	for _, rel := range rels {
+		if rel.Cascade == "" {
+			continue
+		}
+		key := relationKey(e, rel.References)
+		switch {
+		case soft && (rel.Kind == ManyToMany || rel.Cascade == CascadeDetach):
+			continue
+		case rel.Kind == ManyToMany:
+			key = relationKey(e, primaryKeyColumns(e)[0])
+			steps = append(steps, DeleteStep{
+				Table:  rel.JoinTable,
+				Action: CascadeUnlink,
+				Query:  "DELETE FROM " + rel.JoinTable + " WHERE " + rel.JoinForeignKey + " = ?",
+				Args:   []interface{}{key},
+			})
+		case rel.Cascade == CascadeDetach:
+			steps = append(steps, DeleteStep{
+				Table:  rel.Target,
+				Action: CascadeDetach,
+				Query:  "UPDATE " + rel.Target + " SET " + rel.ForeignKey + " = NULL WHERE " + rel.ForeignKey + " = ?",
+				Args:   []interface{}{key},
+			})
+		default:
+			target, _ := entityForType(rel.TargetType)
+			scope := c.WithDeleted()
+			if soft {
+				scope.deleted = excludeDeleted
+			}
+			children, err := scope.plain(nil).selectWhereIn(target, rel.ForeignKey, []interface{}{key})
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				if soft && softDeleteField(child) == nil {
+					continue
+				}
+				childSteps, err := c.planDelete(child, seen, soft)
+				if err != nil {
+					return nil, err
+				}
+				steps = append(steps, childSteps...)
+			}
+		}
+	}
+	if soft {
+		f := softDeleteField(e)
+		if !f.Value.CanSet() {
+			return nil, fmt.Errorf("%s: cannot set %s, pass a pointer", e.GetTable(), f.Field.Name)
+		}
+		c.keep(e)
+		if err := setTime(f.Value, c.now()); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", e.GetTable(), f.Field.Name, err)
+		}
+		return append(steps, DeleteStep{
+			Table:  e.GetTable(),
+			Action: CascadeSoftDelete,
+			Query:  "UPDATE " + e.GetTable() + " SET " + f.Field.Tag.Get("column") + " = ? WHERE ID = ?",
+			Args:   []interface{}{f.Value.Interface(), id},
+			ent:    e,
+		}), nil
+	}
+	for _, rel := range c.inverseJoins(e) {
+		steps = append(steps, DeleteStep{
+			Table:  rel.JoinTable,
+			Action: CascadeUnlink,
+			Query:  "DELETE FROM " + rel.JoinTable + " WHERE " + rel.JoinReferences + " = ?",
+			Args:   []interface{}{relationKey(e, rel.References)},
+		})
+	}
+	steps = append(steps, DeleteStep{
+		Table:  e.GetTable(),
+		Action: CascadeDelete,
+		Query:  "DELETE FROM " + e.GetTable() + " WHERE ID = ?",
+		Args:   []interface{}{id},
+		ent:    e,
+	})
+	return steps, nil
+}
+
+func (c Repository) cascades(e Entity) bool {
+	rels, err := c.Relations(e)
+	if err != nil {
+		return false
+	}
+	for _, rel := range rels {
+		if rel.Cascade != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Repository) inverseJoins(e Entity) []Relation {
+	results := make([]Relation, 0)
+	for _, rel := range c.registry().All() {
+		if rel.Kind == ManyToMany && rel.TargetType == entityType(e) {
+			results = append(results, rel)
+		}
+	}
+	return results
+}
+
+func (c Repository) hardDelete(e Entity, id string) error {
+	if !c.cascades(e) && len(c.inverseJoins(e)) == 0 {
+		_, err := c.exec(stmt(e, OpDelete, "DELETE FROM "+e.GetTable()+" WHERE ID = ?", id))
+		c.invalidate(e.GetTable())
+		return handleSQLError(nil, e, "DELETE", err, id)
+	}
+	return c.cascadeDelete(e, false)
+}
+
+func (c Repository) cascadeDelete(e Entity, soft bool) error {
+	return c.WithTx(c.context(), func(tx Repository) error {
+		steps, err := tx.planDelete(e, make(map[string]bool), soft)
+		if err != nil {
+			return err
+		}
+		for _, s := range steps {
+			child := s.ent != nil && s.ent != e
+			if child {
+				if err := tx.callHook(s.ent, "BeforeDelete", beforeHook(s.ent, hookDelete)); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.exec(tableStmt(s.Table, OpDelete, s.Query, s.Args...)); err != nil {
+				return sqlError(s.Table, strings.ToUpper(s.Action), "", err)
+			}
+			tx.invalidate(s.Table)
+			if child {
+				if err := tx.callHook(s.ent, "AfterDelete", afterHook(s.ent, hookDelete)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
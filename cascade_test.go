@@ -0,0 +1,53 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+type bin struct {
+	widget
+	Parts []*part `foreignKey:"gizmo_id" cascade:"delete"`
+}
+
+func (b *bin) GetTable() string {
+	return "bin"
+}
+
+func TestSoftDeleteCascadesToSoftDeleteChildren(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("FROM part WHERE gizmo_id IN", partColumns, partRow("p1", "b1"))
+	b := &bin{}
+	b.ID = "b1"
+	if err := (Repository{DB: db, Clock: fixedClock}).Delete(b); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"BEGIN",
+		"SELECT * FROM part WHERE gizmo_id IN (?) AND deleted_at IS NULL -- b1",
+		"UPDATE part SET deleted_at = ? WHERE ID = ? -- 2024-01-02 03:04:05,p1",
+		"UPDATE bin SET deleted_at = ? WHERE ID = ? -- 2024-01-02 03:04:05,b1",
+		"COMMIT",
+	}
+	if got := d.statements(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if b.DeletedAt == nil {
+		t.Fatal("deleted_at not set on the parent")
+	}
+}
+
+func TestHardDeleteRemovesInverseJoinRows(t *testing.T) {
+	d, db := newFakeDB(t)
+	repo := Repository{DB: db}
+	repo.RegisterTable(&crate{}, &maker{})
+	m := &maker{}
+	m.ID = "m1"
+	if err := repo.HardDelete(m); err != nil {
+		t.Fatal(err)
+	}
+	unlink := d.count("DELETE FROM crate_maker WHERE maker_id = ? -- m1")
+	if unlink != 1 || d.count("DELETE FROM maker WHERE ID = ? -- m1") != 1 {
+		t.Fatalf("unexpected statements %q", d.statements())
+	}
+}
@@ -1,42 +1,66 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	"errors"
-	"log"
+	"fmt"
+	"strconv"
 	"strings"
 )
 
 func (db DB) Take(dst *Entity, params ...string) error {
-	var e Entity
-	if dst != nil {
-		e = *dst
+	if dst == nil || *dst == nil {
+		return errors.New("take: nil destination")
 	}
-	var table, column, value string
-	rows, err := db.Conn.Query("SELECT * FROM ? WHERE ? = ?", table, column, value)
-	if err != nil {
-		log.Fatal(err)
+	e := *dst
+	column, value := "id", ""
+	switch len(params) {
+	case 0:
+	case 1:
+		value = params[0]
+	default:
+		column, value = params[0], params[1]
+	}
+	if !hasColumn(e, column) {
+		return fmt.Errorf("take %s: unknown column %q", e.GetTable(), column)
 	}
+	st := stmt(e, OpSelect, "SELECT * FROM "+e.GetTable()+" WHERE "+column+" = ? LIMIT 1", redactColumn(e, column, value))
+	st.Query = true
+	if err := db.run(context.Background(), db.Conn, st); err != nil {
+		return handleSQLError(nil, e, "SELECT", err, value)
+	}
+	rows := st.Rows
 	defer rows.Close()
-
-	results := make([]Entity, 0)
-	if err := e.Scan(rows, results); err != nil {
-		return err
+	var err error
+	if rows.Next() {
+		err = e.ScanLocal(rows, e)
+	} else if err = rows.Err(); err == nil {
+		err = sql.ErrNoRows
 	}
-	if len(results) > 0 {
-		dst = &results[0]
-	} else {
-		return errors.New("0 results found")
+	return handleSQLError(rows, e, "SELECT", err, value)
+}
+
+func hasColumn(e Entity, column string) bool {
+	for _, c := range GetColumns(e) {
+		if strings.EqualFold(c, column) {
+			return true
+		}
 	}
-	return nil
+	return false
 }
 
 func (db *DB) QueryBuilder(dst *Entity) *DB {
 	db.query = ""
+	db.args = nil
+	db.err = nil
 	return db
 }
 
 func (db *DB) Select(table, tableAlias, columnAlias string, column []string) *DB {
 	db.query = "SELECT "
+	db.args = nil
+	db.err = nil
 	for i, v := range column {
 		column[i] = columnAlias + "." + v
 	}
@@ -70,3 +94,94 @@ func (db *DB) Or() *DB {
 	db.query += " OR"
 	return db
 }
+
+func (db *DB) OrderBy(t Table, direction string) *DB {
+	if strings.Contains(db.query, " ORDER BY ") {
+		db.query += ", "
+	} else {
+		db.query += " ORDER BY "
+	}
+	db.query += t.Alias + "." + t.Key + " " + strings.ToUpper(direction)
+	return db
+}
+
+func (db *DB) Limit(n int) *DB {
+	db.query += " LIMIT " + strconv.Itoa(n)
+	return db
+}
+
+func (db *DB) Offset(n int) *DB {
+	db.query += " OFFSET " + strconv.Itoa(n)
+	return db
+}
+
+func (db *DB) Paginate(page, size int) *DB {
+	if page < 1 {
+		page = 1
+	}
+	return db.Limit(size).Offset((page - 1) * size)
+}
+
+func (db *DB) After(cursor string, keys ...Table) *DB {
+	return db.keyset(cursor, ">", keys)
+}
+
+func (db *DB) Before(cursor string, keys ...Table) *DB {
+	return db.keyset(cursor, "<", keys)
+}
+
+func (db *DB) keyset(cursor string, op string, keys []Table) *DB {
+	values, err := DecodeCursor(cursor)
+	if err != nil {
+		db.err = err
+		return db
+	}
+	if len(values) != len(keys) {
+		db.err = fmt.Errorf("cursor has %d values for %d keys", len(values), len(keys))
+		return db
+	}
+	columns := make([]string, 0)
+	for _, t := range keys {
+		columns = append(columns, t.Alias+"."+t.Key)
+	}
+	if strings.Contains(db.query, " WHERE ") {
+		db.query += " AND "
+	} else {
+		db.query += " WHERE "
+	}
+	db.query += "(" + strings.Join(columns, ", ") + ") " + op + " (" + strings.Join(placeholderList(len(values)), ", ") + ")"
+	db.args = append(db.args, values...)
+	return db
+}
+
+func (db *DB) ForUpdate() *DB {
+	db.query += lockModeSQL(db.GetDialect(), LockForUpdate)
+	return db
+}
+
+func (db *DB) ForShare() *DB {
+	db.query += lockModeSQL(db.GetDialect(), LockForShare)
+	return db
+}
+
+func (db *DB) NoWait() *DB {
+	db.query += lockWaitSQL(db.GetDialect(), LockNoWait)
+	return db
+}
+
+func (db *DB) SkipLocked() *DB {
+	db.query += lockWaitSQL(db.GetDialect(), LockSkipLocked)
+	return db
+}
+
+func (db DB) GetQuery() string {
+	return db.query
+}
+
+func (db DB) GetArgs() []interface{} {
+	return db.args
+}
+
+func (db DB) Err() error {
+	return db.err
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var ErrNotFound = errors.New("not found")
+
+var ErrDuplicateKey = errors.New("duplicate key")
+
+var ErrForeignKeyViolation = errors.New("foreign key violation")
+
+var ErrDeadlock = errors.New("deadlock")
+
+var ErrLockTimeout = errors.New("lock wait timeout")
+
+var ErrSerializationFailure = errors.New("serialization failure")
+
+var ErrStaleEntity = errors.New("stale entity")
+
+var ErrNoTransaction = errors.New("row locks require a transaction")
+
+type Error struct {
+	Op    string
+	Table string
+	ID    string
+	Kind  error
+	Err   error
+}
+
+func (e *Error) Error() string {
+	s := e.Table + " " + e.Op
+	if e.ID != "" {
+		s += " " + strconv.Quote(e.ID)
+	}
+	if e.Kind != nil && !errors.Is(e.Err, e.Kind) {
+		s += ": " + e.Kind.Error()
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *Error) Unwrap() []error {
+	results := make([]error, 0)
+	if e.Kind != nil {
+		results = append(results, e.Kind)
+	}
+	if e.Err != nil {
+		results = append(results, e.Err)
+	}
+	return results
+}
+
+func sqlError(table string, op string, id string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Op: op, Table: table, ID: id, Kind: classify(err), Err: err}
+}
+
+func classify(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1062:
+			return ErrDuplicateKey
+		case 1451, 1452:
+			return ErrForeignKeyViolation
+		case 1213:
+			return ErrDeadlock
+		case 1205:
+			return ErrLockTimeout
+		}
+		return nil
+	}
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		switch pgErr.SQLState() {
+		case "23505":
+			return ErrDuplicateKey
+		case "23503":
+			return ErrForeignKeyViolation
+		case "40P01":
+			return ErrDeadlock
+		case "40001":
+			return ErrSerializationFailure
+		case "55P03":
+			return ErrLockTimeout
+		}
+		return nil
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"), strings.Contains(msg, "PRIMARY KEY constraint failed"):
+		return ErrDuplicateKey
+	case strings.Contains(msg, "FOREIGN KEY constraint failed"):
+		return ErrForeignKeyViolation
+	case strings.Contains(msg, "database is locked"), strings.Contains(msg, "database table is locked"), strings.Contains(msg, "SQLITE_BUSY"):
+		return ErrLockTimeout
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type pgError string
+
+func (e pgError) Error() string {
+	return "pq: " + string(e)
+}
+
+func (e pgError) SQLState() string {
+	return string(e)
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		kind error
+	}{
+		{sql.ErrNoRows, ErrNotFound},
+		{&mysql.MySQLError{Number: 1062}, ErrDuplicateKey},
+		{&mysql.MySQLError{Number: 1452}, ErrForeignKeyViolation},
+		{&mysql.MySQLError{Number: 1213}, ErrDeadlock},
+		{&mysql.MySQLError{Number: 1205}, ErrLockTimeout},
+		{pgError("23505"), ErrDuplicateKey},
+		{pgError("40P01"), ErrDeadlock},
+		{pgError("40001"), ErrSerializationFailure},
+		{pgError("55P03"), ErrLockTimeout},
+		{errors.New("UNIQUE constraint failed: widget.id"), ErrDuplicateKey},
+		{errors.New("database is locked"), ErrLockTimeout},
+		{errors.New("database is locked (5) (SQLITE_BUSY)"), ErrLockTimeout},
+		{fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: 1213}), ErrDeadlock},
+		{errors.New("syntax error"), nil},
+	} {
+		if got := classify(tc.err); got != tc.kind {
+			t.Errorf("%v: got %v, want %v", tc.err, got, tc.kind)
+		}
+	}
+}
+
+func TestSQLErrorWrapsDriverError(t *testing.T) {
+	driverErr := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	err := sqlError("widget", "INSERT", "w1", driverErr)
+	var myErr *mysql.MySQLError
+	if !errors.Is(err, ErrDuplicateKey) || !errors.As(err, &myErr) || myErr != driverErr {
+		t.Fatalf("classification or driver error lost: %v", err)
+	}
+	if !strings.Contains(err.Error(), `widget INSERT "w1"`) {
+		t.Fatalf("unexpected message %q", err)
+	}
+}
+
+func TestDBTakeRejectsUnknownColumns(t *testing.T) {
+	d, db := newFakeDB(t)
+	var e Entity = &widget{}
+	if err := db.Take(&e, "1 = 1 OR name", "x"); err == nil || !strings.Contains(err.Error(), "unknown column") {
+		t.Fatalf("expected an unknown column error, got %v", err)
+	}
+	if len(d.statements()) != 0 {
+		t.Fatalf("query ran: %q", d.statements())
+	}
+	if err := db.Take(&e, "NAME", "x"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,375 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+type fakeFailure struct {
+	match string
+	err   error
+	times int
+}
+
+type fakeDriver struct {
+	mu       sync.Mutex
+	log      []string
+	results  map[string]fakeRows
+	failures []*fakeFailure
+	affected int64
+	lastID   int64
+	checked  []interface{}
+}
+
+var fakeDrivers atomic.Int64
+
+func newFakeDB(t *testing.T) (*fakeDriver, *DB) {
+	t.Helper()
+	d := &fakeDriver{results: make(map[string]fakeRows), affected: 1}
+	name := fmt.Sprintf("fake%d", fakeDrivers.Add(1))
+	sql.Register(name, d)
+	conn, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	return d, &DB{Conn: conn}
+}
+
+func (d *fakeDriver) returns(match string, cols []string, rows ...[]driver.Value) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.results[match] = fakeRows{cols: cols, rows: rows}
+}
+
+func (d *fakeDriver) failOn(match string, err error, times int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.failures = append(d.failures, &fakeFailure{match: match, err: err, times: times})
+}
+
+func (d *fakeDriver) statements() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append(make([]string, 0), d.log...)
+}
+
+func (d *fakeDriver) count(match string) int {
+	n := 0
+	for _, s := range d.statements() {
+		if strings.Contains(s, match) {
+			n++
+		}
+	}
+	return n
+}
+
+func (d *fakeDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.log = nil
+}
+
+func (d *fakeDriver) record(q string, args []driver.NamedValue) error {
+	_, err := d.recordLine(q, args)
+	return err
+}
+
+func (d *fakeDriver) recordLine(q string, args []driver.NamedValue) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	values := make([]string, 0)
+	for _, a := range args {
+		values = append(values, fmt.Sprint(a.Value))
+	}
+	if len(values) > 0 {
+		q += " -- " + strings.Join(values, ",")
+	}
+	d.log = append(d.log, q)
+	for i, f := range d.failures {
+		if !strings.Contains(q, f.match) {
+			continue
+		}
+		if f.times > 0 {
+			f.times--
+			if f.times == 0 {
+				d.failures = append(d.failures[:i], d.failures[i+1:]...)
+			}
+		}
+		return q, f.err
+	}
+	return q, nil
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{d: d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(q string) (driver.Stmt, error) {
+	return &fakeStmt{c: c, q: q}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.d.record("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return &fakeTx{c: c}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, q string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.d.record(q, args); err != nil {
+		return nil, err
+	}
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	return fakeResult{lastID: c.d.lastID, affected: c.d.affected}, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, q string, args []driver.NamedValue) (driver.Rows, error) {
+	line, err := c.d.recordLine(q, args)
+	if err != nil {
+		return nil, err
+	}
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	best := ""
+	found := false
+	for match := range c.d.results {
+		if strings.Contains(line, match) && (!found || len(match) > len(best)) {
+			best, found = match, true
+		}
+	}
+	r := c.d.results[best]
+	return &fakeCursor{cols: r.cols, rows: r.rows}, nil
+}
+
+func (c *fakeConn) CheckNamedValue(v *driver.NamedValue) error {
+	c.d.mu.Lock()
+	c.d.checked = append(c.d.checked, v.Value)
+	c.d.mu.Unlock()
+	if valuer, ok := v.Value.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		v.Value = value
+		return err
+	}
+	value, err := driver.DefaultParameterConverter.ConvertValue(v.Value)
+	if err != nil {
+		return driver.ErrSkip
+	}
+	v.Value = value
+	return nil
+}
+
+type fakeTx struct {
+	c *fakeConn
+}
+
+func (t *fakeTx) Commit() error {
+	return t.c.d.record("COMMIT", nil)
+}
+
+func (t *fakeTx) Rollback() error {
+	return t.c.d.record("ROLLBACK", nil)
+}
+
+type fakeResult struct {
+	lastID   int64
+	affected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	return r.lastID, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
+
+type fakeStmt struct {
+	c *fakeConn
+	q string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.c.ExecContext(context.Background(), s.q, nil)
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.QueryContext(context.Background(), s.q, nil)
+}
+
+type fakeCursor struct {
+	cols []string
+	rows [][]driver.Value
+	i    int
+}
+
+func (r *fakeCursor) Columns() []string {
+	return r.cols
+}
+
+func (r *fakeCursor) Close() error {
+	return nil
+}
+
+func (r *fakeCursor) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.i])
+	r.i++
+	return nil
+}
+
+type widget struct {
+	SoftDeleteModel
+	Name    string `json:"name" column:"name" datatype:"string"`
+	Version int64  `json:"version" column:"version" datatype:"int" version:"true"`
+}
+
+var widgetColumns = []string{"id", "created", "updated", "deleted_at", "name", "version"}
+
+func widgetRow(id string, name string, version int64) []driver.Value {
+	return []driver.Value{id, "2024-01-02 03:04:05", "2024-01-02 03:04:05", nil, name, version}
+}
+
+func (w *widget) Scan(rows *sql.Rows, results []Entity) error {
+	return nil
+}
+
+func (w *widget) ScanLocal(rows *sql.Rows, e Entity) error {
+	x := e.(*widget)
+	return rows.Scan(&x.ID, &x.Created, &x.Updated, &x.DeletedAt, &x.Name, &x.Version)
+}
+
+func (w *widget) GetTable() string {
+	return "widget"
+}
+
+func (w *widget) SetCreateTable(map[string][]Column) Entity {
+	return w
+}
+
+func (w *widget) GetCreateTable() map[string][]Column {
+	return nil
+}
+
+func (w *widget) GetID() (string, error) {
+	return w.ID, nil
+}
+
+func (w *widget) GetChildren() ([]Entity, error) {
+	return nil, nil
+}
+
+func (w *widget) GetJoin(Entity) (IJoinTable, error) {
+	return nil, nil
+}
+
+func fixedClock() time.Time {
+	return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+}
+
+func typeValue(ptr interface{}) reflect.Value {
+	return reflect.ValueOf(ptr).Elem()
+}
+
+func scanByColumn(rows *sql.Rows, e Entity) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	row := make(map[string]interface{})
+	for i, col := range columns {
+		row[col] = values[i]
+	}
+	return assignColumns(e, row)
+}
+
+type maker struct {
+	widget
+}
+
+func (m *maker) GetTable() string {
+	return "maker"
+}
+
+func (m *maker) ScanLocal(rows *sql.Rows, e Entity) error {
+	return scanByColumn(rows, e)
+}
+
+type part struct {
+	widget
+	GizmoID string `column:"gizmo_id"`
+}
+
+func (p *part) GetTable() string {
+	return "part"
+}
+
+func (p *part) ScanLocal(rows *sql.Rows, e Entity) error {
+	return scanByColumn(rows, e)
+}
+
+type gizmo struct {
+	widget
+	Maker *maker  `column:"maker_id" references:"maker"`
+	Parts []*part `foreignKey:"gizmo_id"`
+}
+
+func (g *gizmo) GetTable() string {
+	return "gizmo"
+}
+
+func (g *gizmo) ScanLocal(rows *sql.Rows, e Entity) error {
+	return scanByColumn(rows, e)
+}
+
+func partRow(id string, gizmo string) []driver.Value {
+	return append(widgetRow(id, id, 0), gizmo)
+}
+
+var partColumns = append(append(make([]string, 0), widgetColumns...), "gizmo_id")
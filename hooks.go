@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+type AfterInserter interface {
+	AfterInsert(ctx context.Context) error
+}
+
+type BeforeUpdater interface {
+	BeforeUpdate(ctx context.Context) error
+}
+
+type AfterUpdater interface {
+	AfterUpdate(ctx context.Context) error
+}
+
+type BeforeDeleter interface {
+	BeforeDelete(ctx context.Context) error
+}
+
+type AfterDeleter interface {
+	AfterDelete(ctx context.Context) error
+}
+
+type AfterLoader interface {
+	AfterLoad(ctx context.Context) error
+}
+
+type hookOp string
+
+const (
+	hookInsert hookOp = "Insert"
+	hookUpdate hookOp = "Update"
+	hookDelete hookOp = "Delete"
+)
+
+func (c Repository) saveHook(ent Entity) hookOp {
+	if c.tracker != nil && c.tracker.Tracked(ent) {
+		return hookUpdate
+	}
+	return hookInsert
+}
+
+func beforeHook(ent Entity, op hookOp) func(context.Context) error {
+	switch op {
+	case hookInsert:
+		if h, ok := ent.(BeforeInserter); ok {
+			return h.BeforeInsert
+		}
+	case hookUpdate:
+		if h, ok := ent.(BeforeUpdater); ok {
+			return h.BeforeUpdate
+		}
+	case hookDelete:
+		if h, ok := ent.(BeforeDeleter); ok {
+			return h.BeforeDelete
+		}
+	}
+	return nil
+}
+
+func afterHook(ent Entity, op hookOp) func(context.Context) error {
+	switch op {
+	case hookInsert:
+		if h, ok := ent.(AfterInserter); ok {
+			return h.AfterInsert
+		}
+	case hookUpdate:
+		if h, ok := ent.(AfterUpdater); ok {
+			return h.AfterUpdate
+		}
+	case hookDelete:
+		if h, ok := ent.(AfterDeleter); ok {
+			return h.AfterDelete
+		}
+	}
+	return nil
+}
+
+func (c Repository) callHook(ent Entity, name string, hook func(context.Context) error) error {
+	if hook == nil {
+		return nil
+	}
+	if err := hook(c.context()); err != nil {
+		return fmt.Errorf("%s %s: %w", ent.GetTable(), name, err)
+	}
+	return nil
+}
+
+func (c Repository) runHooks(ent Entity, op hookOp, fn func(Repository) error) error {
+	if err := c.callHook(ent, "Before"+string(op), beforeHook(ent, op)); err != nil {
+		return err
+	}
+	run := func(r Repository) error {
+		if err := fn(r); err != nil {
+			return err
+		}
+		return r.callHook(ent, "After"+string(op), afterHook(ent, op))
+	}
+	if c.tx == nil && afterHook(ent, op) != nil {
+		return c.WithTx(c.context(), run)
+	}
+	return run(c)
+}
+
+func (c Repository) afterLoad(ent Entity) error {
+	if h, ok := ent.(AfterLoader); ok {
+		return c.callHook(ent, "AfterLoad", h.AfterLoad)
+	}
+	return nil
+}
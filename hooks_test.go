@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var hookLog []string
+
+type hooked struct {
+	widget
+	GizmoID string `column:"gizmo_id"`
+	fail    bool
+}
+
+func (h *hooked) GetTable() string {
+	return "hooked"
+}
+
+func (h *hooked) ScanLocal(rows *sql.Rows, e Entity) error {
+	return scanByColumn(rows, e)
+}
+
+func (h *hooked) record(name string) error {
+	hookLog = append(hookLog, name+" "+h.ID)
+	if h.fail {
+		return errors.New("rejected")
+	}
+	return nil
+}
+
+func (h *hooked) BeforeInsert(ctx context.Context) error { return h.record("BeforeInsert") }
+func (h *hooked) AfterInsert(ctx context.Context) error  { return h.record("AfterInsert") }
+func (h *hooked) BeforeUpdate(ctx context.Context) error { return h.record("BeforeUpdate") }
+func (h *hooked) AfterUpdate(ctx context.Context) error  { return h.record("AfterUpdate") }
+func (h *hooked) BeforeDelete(ctx context.Context) error { return h.record("BeforeDelete") }
+func (h *hooked) AfterDelete(ctx context.Context) error  { return h.record("AfterDelete") }
+
+type hookedBin struct {
+	widget
+	Items []*hooked `foreignKey:"gizmo_id" cascade:"delete"`
+}
+
+func (b *hookedBin) GetTable() string {
+	return "hooked_bin"
+}
+
+func checkHooks(t *testing.T, want ...string) {
+	t.Helper()
+	if strings.Join(hookLog, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("hooks: got %q, want %q", hookLog, want)
+	}
+	hookLog = nil
+}
+
+func TestSaveRunsInsertHooks(t *testing.T) {
+	hookLog = nil
+	_, db := newFakeDB(t)
+	h := &hooked{}
+	h.ID = "h1"
+	if err := (Repository{DB: db}).Save(h); err != nil {
+		t.Fatal(err)
+	}
+	checkHooks(t, "BeforeInsert h1", "AfterInsert h1")
+	if err := (Repository{DB: db}).SaveAll([]Entity{h}); err != nil {
+		t.Fatal(err)
+	}
+	checkHooks(t, "BeforeInsert h1", "AfterInsert h1")
+}
+
+func TestTrackedSaveRunsUpdateHooksOnce(t *testing.T) {
+	hookLog = nil
+	_, db := newFakeDB(t)
+	repo := Repository{DB: db}.WithTracking()
+	h := &hooked{}
+	h.ID = "h1"
+	repo.tracker.Track(h)
+	h.Name = "b"
+	if err := repo.Save(h); err != nil {
+		t.Fatal(err)
+	}
+	checkHooks(t, "BeforeUpdate h1", "AfterUpdate h1")
+}
+
+func TestCascadeRunsChildDeleteHooks(t *testing.T) {
+	hookLog = nil
+	d, db := newFakeDB(t)
+	d.returns("FROM hooked WHERE gizmo_id IN", partColumns, partRow("h1", "b1"))
+	b := &hookedBin{}
+	b.ID = "b1"
+	if err := (Repository{DB: db, Clock: fixedClock}).HardDelete(b); err != nil {
+		t.Fatal(err)
+	}
+	checkHooks(t, "BeforeDelete h1", "AfterDelete h1")
+}
+
+func TestHookErrorAbortsSave(t *testing.T) {
+	hookLog = nil
+	d, db := newFakeDB(t)
+	h := &hooked{fail: true}
+	h.ID = "h1"
+	if err := (Repository{DB: db}).Save(h); err == nil || !strings.Contains(err.Error(), "BeforeInsert") {
+		t.Fatalf("expected the hook error, got %v", err)
+	}
+	if d.count("INSERT") != 0 {
+		t.Fatalf("statement ran after a failing hook: %q", d.statements())
+	}
+}
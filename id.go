@@ -0,0 +1,167 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+const GenerateUUIDv4 string = "uuidv4"
+const GenerateUUIDv7 string = "uuidv7"
+const GenerateULID string = "ulid"
+const GenerateAutoIncrement string = "autoincrement"
+
+const crockford string = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func NewUUIDv4() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return formatUUID(b), nil
+}
+
+func NewUUIDv7() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", err
+	}
+	putMillis(b[:6], time.Now())
+	b[6] = (b[6] & 0x0f) | 0x70
+	b[8] = (b[8] & 0x3f) | 0x80
+	return formatUUID(b), nil
+}
+
+func NewULID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", err
+	}
+	putMillis(b[:6], time.Now())
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out), nil
+}
+
+func putMillis(dst []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		dst[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+func formatUUID(b [16]byte) string {
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+func newID(strategy string) (string, error) {
+	switch strategy {
+	case GenerateUUIDv4:
+		return NewUUIDv4()
+	case GenerateUUIDv7:
+		return NewUUIDv7()
+	case GenerateULID:
+		return NewULID()
+	}
+	return "", fmt.Errorf("unknown id strategy %q", strategy)
+}
+
+func checkGenerate(ent Entity) error {
+	for _, f := range columnFields(ent) {
+		strategy, ok := f.Field.Tag.Lookup("generate")
+		if !ok {
+			continue
+		}
+		kind := f.Field.Type.Kind()
+		switch strategy {
+		case GenerateAutoIncrement:
+			if _, ok := autoIncrementType(kind); !ok {
+				return fmt.Errorf("%s: %s needs an integer field, %s is %s", ent.GetTable(), strategy, f.Field.Name, f.Field.Type)
+			}
+		case GenerateUUIDv4, GenerateUUIDv7, GenerateULID:
+			if kind != reflect.String {
+				return fmt.Errorf("%s: %s needs a string field, %s is %s", ent.GetTable(), strategy, f.Field.Name, f.Field.Type)
+			}
+		default:
+			return fmt.Errorf("%s %s: unknown id strategy %q", ent.GetTable(), f.Field.Name, strategy)
+		}
+	}
+	return nil
+}
+
+func generateIDs(ent Entity) error {
+	if err := checkGenerate(ent); err != nil {
+		return err
+	}
+	for _, f := range columnFields(ent) {
+		strategy, ok := f.Field.Tag.Lookup("generate")
+		if !ok || strategy == GenerateAutoIncrement || !f.Value.IsZero() {
+			continue
+		}
+		if !f.Value.CanSet() {
+			return fmt.Errorf("%s: cannot generate %s into %s", ent.GetTable(), strategy, f.Field.Name)
+		}
+		id, err := newID(strategy)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", ent.GetTable(), f.Field.Name, err)
+		}
+		f.Value.SetString(id)
+	}
+	return nil
+}
+
+func insertFields(ent Entity) ([]fieldRef, *fieldRef) {
+	fields := make([]fieldRef, 0)
+	var auto *fieldRef
+	for _, f := range columnFields(ent) {
+		if f.Field.Tag.Get("generate") == GenerateAutoIncrement {
+			a := f
+			auto = &a
+			if f.Value.IsZero() {
+				continue
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, auto
+}
+
+func setInsertID(auto *fieldRef, res sql.Result) error {
+	if auto == nil || !auto.Value.IsZero() {
+		return nil
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	return setAutoID(auto, id)
+}
+
+func setAutoID(auto *fieldRef, id int64) error {
+	if !auto.Value.CanSet() {
+		return fmt.Errorf("cannot store insert id in %s, pass a pointer", auto.Field.Name)
+	}
+	switch auto.Value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		auto.Value.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		auto.Value.SetUint(uint64(id))
+	default:
+		return fmt.Errorf("cannot store insert id in %s", auto.Field.Name)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type counter struct {
+	widget
+	Seq int64 `column:"seq" generate:"autoincrement"`
+}
+
+type badCounter struct {
+	widget
+	Seq string `column:"seq" generate:"autoincrement"`
+}
+
+func TestGeneratedIDs(t *testing.T) {
+	for strategy, length := range map[string]int{GenerateUUIDv4: 36, GenerateUUIDv7: 36, GenerateULID: 26} {
+		id, err := newID(strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(id) != length {
+			t.Fatalf("%s: %q has length %d", strategy, id, len(id))
+		}
+	}
+}
+
+func TestAutoIncrementColumnType(t *testing.T) {
+	columns := make(map[string][]Column)
+	processTypeForColumns(reflect.TypeOf(counter{}), columns, "counter")
+	for _, c := range columns["counter"] {
+		if c.ColumnString != "seq" {
+			continue
+		}
+		c.GenerateSQL()
+		if !strings.Contains(c.SQLDefinition, "BIGINT NOT NULL AUTO_INCREMENT") {
+			t.Fatalf("seq = %q", c.SQLDefinition)
+		}
+		return
+	}
+	t.Fatal("no seq column")
+}
+
+func TestAutoIncrementWritesInsertID(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.lastID = 42
+	c := &counter{}
+	if err := (Repository{DB: db}).Insert(c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Seq != 42 {
+		t.Fatalf("seq = %d", c.Seq)
+	}
+	if strings.Contains(d.statements()[0], "seq") {
+		t.Fatalf("insert wrote the auto increment column: %s", d.statements()[0])
+	}
+}
+
+func TestAutoIncrementRejectsStrings(t *testing.T) {
+	_, db := newFakeDB(t)
+	if err := (Repository{DB: db}).Insert(&badCounter{}); err == nil {
+		t.Fatal("expected an error for a string auto increment field")
+	}
+	if err := (Repository{DB: db, Tables: []Entity{&badCounter{}}}).CreateTables(); err == nil {
+		t.Fatal("expected CreateTables to reject a string auto increment field")
+	}
+}
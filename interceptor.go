@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	OpSelect   string = "SELECT"
+	OpCount    string = "COUNT"
+	OpInsert   string = "INSERT"
+	OpSave     string = "SAVE"
+	OpUpdate   string = "UPDATE"
+	OpDelete   string = "DELETE"
+	OpRestore  string = "RESTORE"
+	OpLoad     string = "LOAD"
+	OpDDL      string = "DDL"
+	OpBegin    string = "BEGIN"
+	OpCommit   string = "COMMIT"
+	OpRollback string = "ROLLBACK"
+)
+
+type Statement struct {
+	SQL          string
+	Args         []interface{}
+	Entity       Entity
+	Table        string
+	Operation    string
+	Query        bool
+	Attempt      int
+	Started      time.Time
+	Duration     time.Duration
+	RowsAffected int64
+	Rows         *sql.Rows
+	Result       sql.Result
+	Err          error
+}
+
+type Handler func(ctx context.Context, st *Statement) error
+
+type Interceptor func(next Handler) Handler
+
+type sqlConn interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (d *DB) Use(interceptors ...Interceptor) {
+	d.interceptors = append(d.interceptors, interceptors...)
+}
+
+func stmt(ent Entity, op string, q string, args ...interface{}) *Statement {
+	st := &Statement{SQL: q, Args: args, Entity: ent, Operation: op, Attempt: 1, RowsAffected: -1}
+	if ent != nil {
+		st.Table = ent.GetTable()
+	}
+	return st
+}
+
+func tableStmt(table string, op string, q string, args ...interface{}) *Statement {
+	return &Statement{SQL: q, Args: args, Table: table, Operation: op, Attempt: 1, RowsAffected: -1}
+}
+
+func (d DB) run(ctx context.Context, conn sqlConn, st *Statement) error {
+	err := d.intercept(ctx, st, func(ctx context.Context, st *Statement) error {
+		st.SQL = rebind(d.GetDialect(), st.SQL)
+		st.Started = time.Now()
+		if st.Query {
+			st.Rows, st.Err = conn.QueryContext(ctx, st.SQL, unredact(st.Args)...)
+		} else {
+			st.Result, st.Err = conn.ExecContext(ctx, st.SQL, unredact(st.Args)...)
+			if st.Err == nil {
+				if n, err := st.Result.RowsAffected(); err == nil {
+					st.RowsAffected = n
+				}
+			}
+		}
+		st.Duration = time.Since(st.Started)
+		return st.Err
+	})
+	switch {
+	case err != nil:
+		return err
+	case st.Query && st.Rows == nil:
+		return fmt.Errorf("%s %s: an interceptor returned without rows", st.Table, st.Operation)
+	case !st.Query && st.Result == nil:
+		return fmt.Errorf("%s %s: an interceptor returned without a result", st.Table, st.Operation)
+	}
+	return nil
+}
+
+func (d DB) boundary(ctx context.Context, op string, attempt int, fn func(ctx context.Context) error) error {
+	st := &Statement{SQL: op, Operation: op, Attempt: attempt, RowsAffected: -1}
+	ran := false
+	err := d.intercept(ctx, st, func(ctx context.Context, st *Statement) error {
+		ran = true
+		st.Started = time.Now()
+		st.Err = fn(ctx)
+		st.Duration = time.Since(st.Started)
+		return st.Err
+	})
+	if err == nil && !ran {
+		return fmt.Errorf("%s: an interceptor returned without running it", op)
+	}
+	return err
+}
+
+func (d DB) intercept(ctx context.Context, st *Statement, h Handler) error {
+	for i := len(d.interceptors) - 1; i >= 0; i-- {
+		h = d.interceptors[i](h)
+	}
+	return h(ctx, st)
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestInterceptorShortCircuitReturnsError(t *testing.T) {
+	d, db := newFakeDB(t)
+	db.Use(func(next Handler) Handler {
+		return func(ctx context.Context, st *Statement) error {
+			return nil
+		}
+	})
+	if err := (Repository{DB: db}).Take(&widget{}, "w1"); err == nil {
+		t.Fatal("expected an error when an interceptor skips a query")
+	}
+	if err := (Repository{DB: db}).Delete(&widget{}); err == nil {
+		t.Fatal("expected an error when an interceptor skips an exec")
+	}
+	if len(d.statements()) != 0 {
+		t.Fatalf("statements reached the driver: %q", d.statements())
+	}
+}
+
+func TestInterceptorsSeeTransactionBoundaries(t *testing.T) {
+	_, db := newFakeDB(t)
+	ops := make([]string, 0)
+	db.Use(func(next Handler) Handler {
+		return func(ctx context.Context, st *Statement) error {
+			ops = append(ops, st.Operation)
+			return next(ctx, st)
+		}
+	})
+	repo := Repository{DB: db}
+	if err := repo.WithTx(context.Background(), func(tx Repository) error {
+		_, err := tx.exec(tableStmt("widget", OpDelete, "DELETE FROM widget"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	boom := errors.New("boom")
+	if err := repo.WithTx(context.Background(), func(tx Repository) error {
+		return boom
+	}); !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	want := []string{OpBegin, OpDelete, OpCommit, OpBegin, OpRollback}
+	if len(ops) != len(want) {
+		t.Fatalf("got %q, want %q", ops, want)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Fatalf("got %q, want %q", ops, want)
+		}
+	}
+}
+
+func TestInterceptorSkippingBeginFails(t *testing.T) {
+	d, db := newFakeDB(t)
+	db.Use(func(next Handler) Handler {
+		return func(ctx context.Context, st *Statement) error {
+			if st.Operation == OpBegin {
+				return nil
+			}
+			return next(ctx, st)
+		}
+	})
+	called := false
+	err := Repository{DB: db}.WithTx(context.Background(), func(tx Repository) error {
+		called = true
+		return nil
+	})
+	if err == nil || called || d.count("BEGIN") != 0 {
+		t.Fatalf("expected WithTx to fail before running fn, got %v", err)
+	}
+}
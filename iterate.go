@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"reflect"
+)
+
+func newEntity(proto Entity) Entity {
+	t := reflect.TypeOf(proto)
+	if t.Kind() == reflect.Ptr {
+		return reflect.New(t.Elem()).Interface().(Entity)
+	}
+	return reflect.New(t).Elem().Interface().(Entity)
+}
+
+func prototype[T Entity]() (Entity, error) {
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Interface {
+		return nil, fmt.Errorf("%s is an interface, use a concrete entity type", t)
+	}
+	var zero T
+	return newEntity(zero), nil
+}
+
+func (c Repository) scanInto(rows *sql.Rows, ent Entity) error {
+	var err error
+	if len(c.joins) > 0 {
+		err = c.scanJoined(rows, ent)
+	} else {
+		err = ent.ScanLocal(rows, ent)
+	}
+	if err != nil {
+		return err
+	}
+	if err := c.bindHandles(ent); err != nil {
+		return err
+	}
+	return c.afterLoad(ent)
+}
+
+func (c Repository) scanRow(rows *sql.Rows, proto Entity) (Entity, error) {
+	e := newEntity(proto)
+	if err := c.scanInto(rows, e); err != nil {
+		return nil, err
+	}
+	return c.remember(e), nil
+}
+
+func (c Repository) scanAll(rows *sql.Rows, proto Entity) ([]Entity, error) {
+	results := make([]Entity, 0)
+	for rows.Next() {
+		e, err := c.scanRow(rows, proto)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, e)
+	}
+	return results, rows.Err()
+}
+
+func Iterate[T Entity](ctx context.Context, c Repository, query string, args ...interface{}) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		c.ctx = ctx
+		proto, err := prototype[T]()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		lock, err := c.lockClause(proto)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		if query == "" {
+			query = "SELECT * FROM " + proto.GetTable() + " WHERE 1 = 1" + c.scopeClause(proto, "") + lock
+		} else if lock != "" {
+			yield(zero, fmt.Errorf("%s: write the lock clause into the query passed to Iterate", proto.GetTable()))
+			return
+		}
+		rows, err := c.query(stmt(proto, OpSelect, query, args...))
+		if err != nil {
+			yield(zero, handleSQLError(nil, proto, "SELECT", err, ""))
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			e, err := c.scanRow(rows, proto)
+			if err != nil {
+				yield(zero, fmt.Errorf("scan: %w", err))
+				return
+			}
+			if !yield(e.(T), nil) {
+				return
+			}
+		}
+		if err := handleSQLError(rows, proto, "SELECT", nil, ""); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+func IterateChunks[T Entity](ctx context.Context, c Repository, size int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		c.ctx = ctx
+		if size <= 0 {
+			size = 1000
+		}
+		proto, err := prototype[T]()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		lock, err := c.lockClause(proto)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		key := primaryKeyColumns(proto)[0]
+		base := "SELECT * FROM " + proto.GetTable() + " WHERE 1 = 1" + c.scopeClause(proto, "")
+		var last interface{}
+		for {
+			q := base
+			args := make([]interface{}, 0)
+			if last != nil {
+				q += " AND " + key + " > ?"
+				args = append(args, last)
+			}
+			q += fmt.Sprintf(" ORDER BY %s LIMIT %d", key, size) + lock
+			rows, err := c.query(stmt(proto, OpSelect, q, args...))
+			if err != nil {
+				yield(zero, handleSQLError(nil, proto, "SELECT", err, ""))
+				return
+			}
+			chunk, err := c.scanAll(rows, proto)
+			rows.Close()
+			if err != nil {
+				yield(zero, handleSQLError(nil, proto, "SELECT", err, ""))
+				return
+			}
+			for _, e := range chunk {
+				if !yield(e.(T), nil) {
+					return
+				}
+			}
+			if len(chunk) < size {
+				return
+			}
+			v, ok := columnValue(chunk[len(chunk)-1], key)
+			if !ok {
+				yield(zero, fmt.Errorf("%s: no value for key column %s", proto.GetTable(), key))
+				return
+			}
+			last = v
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestIterateScansRows(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("SELECT * FROM widget", widgetColumns, widgetRow("w1", "a", 0), widgetRow("w2", "b", 0))
+	names := make([]string, 0)
+	for w, err := range Iterate[*widget](context.Background(), Repository{DB: db}, "") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, w.Name)
+	}
+	if strings.Join(names, ",") != "a,b" {
+		t.Fatalf("names = %v", names)
+	}
+	if got := d.statements()[0]; got != "SELECT * FROM widget WHERE 1 = 1 AND deleted_at IS NULL" {
+		t.Fatalf("ran %s", got)
+	}
+}
+
+func TestIterateRejectsInterfaceTypes(t *testing.T) {
+	_, db := newFakeDB(t)
+	for _, err := range Iterate[Entity](context.Background(), Repository{DB: db}, "") {
+		if err == nil || !strings.Contains(err.Error(), "interface") {
+			t.Fatalf("err = %v", err)
+		}
+		return
+	}
+	t.Fatal("Iterate[Entity] yielded nothing")
+}
+
+func TestIterateChunksUsesKeyset(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("ORDER BY id LIMIT 2", widgetColumns, widgetRow("w1", "a", 0), widgetRow("w2", "b", 0))
+	d.returns("AND id > ? ORDER BY id LIMIT 2 -- w2", widgetColumns, widgetRow("w3", "c", 0))
+	n := 0
+	for _, err := range IterateChunks[*widget](context.Background(), Repository{DB: db}, 2) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 3 || len(d.statements()) != 2 {
+		t.Fatalf("read %d rows with %v", n, d.statements())
+	}
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestJoinsLoadsBelongsTo(t *testing.T) {
+	d, db := newFakeDB(t)
+	cols := append(append(make([]string, 0), widgetColumns...), "maker_id", "Maker__id", "Maker__name")
+	d.returns("LEFT JOIN maker j0", cols,
+		append(widgetRow("g1", "a", 0), driver.Value("m1"), driver.Value("m1"), driver.Value("acme")),
+		append(widgetRow("g2", "b", 0), driver.Value(nil), driver.Value(nil), driver.Value(nil)))
+	page, err := Repository{DB: db}.Joins("Maker").List(&gizmo{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := d.statements()[0]
+	if !strings.Contains(q, "j0.name AS Maker__name") || !strings.Contains(q, "LEFT JOIN maker j0 ON j0.id = t.maker_id") {
+		t.Fatalf("unexpected query %s", q)
+	}
+	g1, g2 := page.Items[0].(*gizmo), page.Items[1].(*gizmo)
+	if g1.Maker == nil || g1.Maker.ID != "m1" || g1.Maker.Name != "acme" {
+		t.Fatalf("maker not loaded: %+v", g1.Maker)
+	}
+	if g2.Maker != nil {
+		t.Fatalf("expected no maker for a NULL join, got %+v", g2.Maker)
+	}
+}
+
+func TestJoinsRejectsHasMany(t *testing.T) {
+	_, db := newFakeDB(t)
+	if _, err := (Repository{DB: db}).Joins("Parts").List(&gizmo{}, ""); err == nil {
+		t.Fatal("expected an error joining a has-many relation")
+	}
+}
@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+)
+
+type relationHandle interface {
+	relationTarget() reflect.Type
+	relationMany() bool
+	loadedEntities() []Entity
+	setLoaded(children []Entity)
+}
+
+type relationBinder interface {
+	bind(owner Entity, rel Relation)
+}
+
+type Rel[T Entity] struct {
+	ID     string
+	value  T
+	loaded bool
+}
+
+func NewRel[T Entity](value T) Rel[T] {
+	r := Rel[T]{}
+	r.Set(value)
+	return r
+}
+
+func (r *Rel[T]) Set(value T) {
+	r.value = value
+	r.loaded = true
+	r.ID = ""
+	if !isNilEntity(value) {
+		r.ID, _ = value.GetID()
+	}
+}
+
+func (r Rel[T]) Get() (T, bool) {
+	return r.value, r.loaded
+}
+
+func (r Rel[T]) Loaded() bool {
+	return r.loaded
+}
+
+func (r *Rel[T]) Load(ctx context.Context, c Repository) (T, error) {
+	if r.loaded || r.ID == "" {
+		return r.value, nil
+	}
+	var zero T
+	target := newEntity(zero)
+	if c.identity != nil {
+		if existing, ok := c.identity.Get(target.GetTable(), r.ID); ok {
+			if v, ok := existing.(T); ok {
+				r.value = v
+				r.loaded = true
+				return v, nil
+			}
+		}
+	}
+	if err := c.plain(ctx).Take(target, r.ID); err != nil {
+		return zero, err
+	}
+	r.value = target.(T)
+	r.loaded = true
+	return r.value, nil
+}
+
+func (r Rel[T]) Value() (driver.Value, error) {
+	if r.loaded && !isNilEntity(r.value) {
+		if id, err := r.value.GetID(); err == nil && id != "" {
+			return id, nil
+		}
+	}
+	if r.ID == "" {
+		return nil, nil
+	}
+	return r.ID, nil
+}
+
+func (r *Rel[T]) Scan(src interface{}) error {
+	var zero T
+	r.value = zero
+	r.loaded = false
+	switch v := src.(type) {
+	case nil:
+		r.ID = ""
+	case []byte:
+		r.ID = string(v)
+	case string:
+		r.ID = v
+	default:
+		r.ID = fmt.Sprint(v)
+	}
+	return nil
+}
+
+func (r Rel[T]) relationTarget() reflect.Type {
+	var zero T
+	return entityType(zero)
+}
+
+func (r Rel[T]) relationMany() bool {
+	return false
+}
+
+func (r Rel[T]) loadedEntities() []Entity {
+	if !r.loaded || isNilEntity(r.value) {
+		return []Entity{}
+	}
+	return []Entity{r.value}
+}
+
+func (r *Rel[T]) setLoaded(children []Entity) {
+	var zero T
+	if len(children) == 0 {
+		r.value = zero
+		r.loaded = true
+		return
+	}
+	if v, ok := children[0].(T); ok {
+		r.Set(v)
+	}
+}
+
+type RelMany[T Entity] struct {
+	owner  Entity
+	rel    Relation
+	values []T
+	loaded bool
+}
+
+func (r *RelMany[T]) Set(values ...T) {
+	r.values = values
+	r.loaded = true
+}
+
+func (r RelMany[T]) Get() ([]T, bool) {
+	return r.values, r.loaded
+}
+
+func (r RelMany[T]) Loaded() bool {
+	return r.loaded
+}
+
+func (r *RelMany[T]) Load(ctx context.Context, c Repository) ([]T, error) {
+	if r.loaded {
+		return r.values, nil
+	}
+	if r.owner == nil {
+		return nil, fmt.Errorf("%s: relation is not bound to an owner, read the owner through a Repository first", r.relationTarget().Name())
+	}
+	if _, err := c.plain(ctx).preloadRelation(r.rel, []Entity{r.owner}); err != nil {
+		return nil, err
+	}
+	return r.values, nil
+}
+
+func (r RelMany[T]) relationTarget() reflect.Type {
+	var zero T
+	return entityType(zero)
+}
+
+func (r RelMany[T]) relationMany() bool {
+	return true
+}
+
+func (r RelMany[T]) loadedEntities() []Entity {
+	results := make([]Entity, 0)
+	for _, v := range r.values {
+		if !isNilEntity(v) {
+			results = append(results, v)
+		}
+	}
+	return results
+}
+
+func (r *RelMany[T]) setLoaded(children []Entity) {
+	values := make([]T, 0)
+	for _, child := range children {
+		if v, ok := child.(T); ok {
+			values = append(values, v)
+		}
+	}
+	r.Set(values...)
+}
+
+func (r *RelMany[T]) bind(owner Entity, rel Relation) {
+	r.owner = owner
+	r.rel = rel
+	r.values = nil
+	r.loaded = false
+}
+
+func isRelationHandle(t reflect.Type) bool {
+	_, ok := reflect.New(t).Interface().(relationHandle)
+	return ok
+}
+
+func isNilEntity(ent Entity) bool {
+	v := reflect.ValueOf(ent)
+	return !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil())
+}
+
+func (c Repository) plain(ctx context.Context) Repository {
+	c.preload = nil
+	c.joins = nil
+	c.lock = LockNone
+	c.lockWait = LockWaitBlock
+	c.pagination = pagination{}
+	if ctx != nil {
+		c.ctx = ctx
+	}
+	return c
+}
+
+func (c Repository) bindHandles(ent Entity) error {
+	rels, err := c.Relations(ent)
+	if err != nil {
+		return err
+	}
+	for _, rel := range rels {
+		if !rel.Many() {
+			continue
+		}
+		v := rel.field(ent)
+		if !v.CanAddr() {
+			continue
+		}
+		if b, ok := v.Addr().Interface().(relationBinder); ok {
+			b.bind(ent, rel)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRelLoadDropsLockAndPagination(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("FROM maker", widgetColumns, widgetRow("m1", "acme", 0))
+	repo := Repository{DB: db}.ForUpdate().NoWait().Paginate(3, 10)
+	r := Rel[*maker]{ID: "m1"}
+	m, err := r.Load(context.Background(), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m == nil || m.Name != "acme" || !r.Loaded() {
+		t.Fatalf("relation not loaded: %+v", m)
+	}
+	q := d.statements()[0]
+	if strings.Contains(q, "FOR UPDATE") || strings.Contains(q, "LIMIT 10") || strings.Contains(q, "OFFSET") {
+		t.Fatalf("lazy load kept the caller's clauses: %s", q)
+	}
+}
+
+func TestRelManyLoadNeedsOwner(t *testing.T) {
+	_, db := newFakeDB(t)
+	var r RelMany[*part]
+	if _, err := r.Load(context.Background(), Repository{DB: db}); err == nil {
+		t.Fatal("expected an error for an unbound relation")
+	}
+}
+
+func TestRelLoadInSessionReturnsMappedInstance(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("FROM maker", widgetColumns, widgetRow("m1", "acme", 0))
+	s := Repository{DB: db}.Session()
+	loaded, err := SessionGet[*maker](s, "m1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Rel[*maker]{ID: "m1"}
+	m, err := r.Load(context.Background(), s.Repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != loaded || len(d.statements()) != 1 {
+		t.Fatalf("got %p, want %p after %v", m, loaded, d.statements())
+	}
+	other := Rel[*maker]{ID: "m2"}
+	d.returns("LIMIT 1 -- m2", widgetColumns, widgetRow("m2", "globex", 0))
+	m2, err := other.Load(context.Background(), s.Repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := SessionGet[*maker](s, "m2"); err != nil || again != m2 {
+		t.Fatalf("lazy load not mapped in the session: %p, %p, %v", again, m2, err)
+	}
+}
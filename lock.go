@@ -0,0 +1,79 @@
+package db
+
+import "fmt"
+
+type LockMode int
+
+const (
+	LockNone LockMode = iota
+	LockForUpdate
+	LockForShare
+)
+
+type LockWait int
+
+const (
+	LockWaitBlock LockWait = iota
+	LockNoWait
+	LockSkipLocked
+)
+
+func (c Repository) ForUpdate() Repository {
+	c.lock = LockForUpdate
+	return c
+}
+
+func (c Repository) ForShare() Repository {
+	c.lock = LockForShare
+	return c
+}
+
+func (c Repository) NoWait() Repository {
+	c.lockWait = LockNoWait
+	return c
+}
+
+func (c Repository) SkipLocked() Repository {
+	c.lockWait = LockSkipLocked
+	return c
+}
+
+func (c Repository) lockClause(ent Entity) (string, error) {
+	if c.lock == LockNone {
+		return "", nil
+	}
+	if c.tx == nil {
+		return "", fmt.Errorf("%s: %w", ent.GetTable(), ErrNoTransaction)
+	}
+	mode := lockModeSQL(c.DB.GetDialect(), c.lock)
+	if mode != "" && len(c.joins) > 0 && c.DB.GetDialect() == DialectPostgres {
+		mode += " OF t"
+	}
+	return mode + lockWaitSQL(c.DB.GetDialect(), c.lockWait), nil
+}
+
+func lockModeSQL(dialect string, mode LockMode) string {
+	if dialect == DialectSQLite {
+		return ""
+	}
+	switch mode {
+	case LockForUpdate:
+		return " FOR UPDATE"
+	case LockForShare:
+		return " FOR SHARE"
+	}
+	return ""
+}
+
+func lockWaitSQL(dialect string, wait LockWait) string {
+	if dialect == DialectSQLite {
+		return ""
+	}
+	switch wait {
+	case LockNoWait:
+		return " NOWAIT"
+	case LockSkipLocked:
+		return " SKIP LOCKED"
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLockRequiresTransaction(t *testing.T) {
+	_, db := newFakeDB(t)
+	_, err := Repository{DB: db}.ForUpdate().Select(&widget{}, "w1")
+	if !errors.Is(err, ErrNoTransaction) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestLockClauses(t *testing.T) {
+	for _, tc := range []struct {
+		dialect string
+		lock    func(Repository) Repository
+		want    string
+	}{
+		{DialectMySQL, func(r Repository) Repository { return r.ForUpdate() }, "LIMIT 1 FOR UPDATE --"},
+		{DialectMySQL, func(r Repository) Repository { return r.ForShare().NoWait() }, "LIMIT 1 FOR SHARE NOWAIT --"},
+		{DialectPostgres, func(r Repository) Repository { return r.ForUpdate().SkipLocked() }, "LIMIT 1 FOR UPDATE SKIP LOCKED --"},
+		{DialectSQLite, func(r Repository) Repository { return r.ForUpdate() }, "LIMIT 1 --"},
+	} {
+		d, db := newFakeDB(t)
+		db.SetDialect(tc.dialect)
+		err := Repository{DB: db}.WithTx(context.Background(), func(tx Repository) error {
+			err := tc.lock(tx).Take(&widget{}, "w1")
+			if errors.Is(err, ErrNotFound) {
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.statements()[1]; !strings.Contains(got, tc.want) {
+			t.Fatalf("%s: %s does not contain %q", tc.dialect, got, tc.want)
+		}
+	}
+}
+
+func TestBuilderLocks(t *testing.T) {
+	db := &DB{}
+	q := db.Select("widget", "w", "w", []string{"id"}).ForUpdate().SkipLocked().GetQuery()
+	if !strings.HasSuffix(q, "FOR UPDATE SKIP LOCKED") {
+		t.Fatalf("query = %q", q)
+	}
+}
+
+func TestClaimRowsWithSkipLocked(t *testing.T) {
+	d, db := newFakeDB(t)
+	db.SetDialect(DialectPostgres)
+	d.returns("SELECT", widgetColumns, widgetRow("w1", "queued", 0), widgetRow("w2", "queued", 0))
+	var claimed []Entity
+	err := Repository{DB: db}.WithTx(context.Background(), func(tx Repository) error {
+		page, err := tx.ForUpdate().SkipLocked().Limit(2).List(&widget{}, "name = ?", "queued")
+		claimed = page.Items
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.statements()[1]; !strings.Contains(got, "ORDER BY t.id ASC LIMIT 2 FOR UPDATE SKIP LOCKED") {
+		t.Fatalf("claim ran %s", got)
+	}
+	if len(claimed) != 2 {
+		t.Fatalf("claimed %d rows", len(claimed))
+	}
+}
+
+func TestLockAppliesToAllAndIterate(t *testing.T) {
+	d, db := newFakeDB(t)
+	err := Repository{DB: db}.WithTx(context.Background(), func(tx Repository) error {
+		if _, err := tx.ForShare().All([]string{"w1"}, &widget{}); err != nil {
+			return err
+		}
+		for _, err := range Iterate[*widget](context.Background(), tx.ForUpdate(), "") {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(d.statements()[1], "FOR SHARE -- w1") || !strings.HasSuffix(d.statements()[2], "deleted_at IS NULL FOR UPDATE") {
+		t.Fatalf("ran %v", d.statements())
+	}
+	for _, err := range Iterate[*widget](context.Background(), Repository{DB: db}.ForUpdate(), "") {
+		if !errors.Is(err, ErrNoTransaction) {
+			t.Fatalf("iterate outside a transaction: %v", err)
+		}
+	}
+}
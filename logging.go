@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const redacted string = "[REDACTED]"
+
+type Redacted struct {
+	value interface{}
+}
+
+func Redact(v interface{}) Redacted {
+	return Redacted{value: v}
+}
+
+func (r Redacted) Value() (driver.Value, error) {
+	if valuer, ok := r.value.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return driver.DefaultParameterConverter.ConvertValue(r.value)
+}
+
+func unredact(args []interface{}) []interface{} {
+	results := make([]interface{}, 0, len(args))
+	for _, a := range args {
+		if r, ok := a.(Redacted); ok {
+			a = r.value
+		}
+		results = append(results, a)
+	}
+	return results
+}
+
+func (r Redacted) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+func (r Redacted) String() string {
+	return redacted
+}
+
+type LogOptions struct {
+	Level         slog.Leveler
+	SlowThreshold time.Duration
+}
+
+func LogInterceptor(logger *slog.Logger, opts LogOptions) Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, st *Statement) error {
+			err := next(ctx, st)
+			l := logger
+			if l == nil {
+				l = slog.Default()
+			}
+			level := slog.LevelDebug
+			if opts.Level != nil {
+				level = opts.Level.Level()
+			}
+			msg := "query"
+			slow := opts.SlowThreshold > 0 && st.Duration >= opts.SlowThreshold
+			switch {
+			case err != nil:
+				level = slog.LevelError
+				msg = "query failed"
+			case slow:
+				level = slog.LevelWarn
+				msg = "slow query"
+			}
+			if !l.Enabled(ctx, level) {
+				return err
+			}
+			attrs := []slog.Attr{
+				slog.String("operation", st.Operation),
+				slog.String("table", st.Table),
+				slog.String("sql", st.SQL),
+				slog.Any("args", logArgs(st.Args)),
+				slog.Duration("duration", st.Duration),
+			}
+			if st.RowsAffected >= 0 {
+				attrs = append(attrs, slog.Int64("rows", st.RowsAffected))
+			}
+			if slow {
+				attrs = append(attrs, slog.Bool("slow", true))
+			}
+			if st.Attempt > 1 {
+				attrs = append(attrs, slog.Int("attempt", st.Attempt))
+			}
+			if caller := statementCaller(); caller != "" {
+				attrs = append(attrs, slog.String("caller", caller))
+			}
+			if err != nil {
+				attrs = append(attrs, slog.Any("error", err))
+			}
+			l.LogAttrs(ctx, level, msg, attrs...)
+			return err
+		}
+	}
+}
+
+func logArgs(args []interface{}) []interface{} {
+	results := make([]interface{}, 0)
+	for _, a := range args {
+		if v, ok := a.(slog.LogValuer); ok {
+			results = append(results, v.LogValue().Resolve().Any())
+			continue
+		}
+		results = append(results, a)
+	}
+	return results
+}
+
+var packagePath = reflect.TypeOf(Statement{}).PkgPath()
+
+func statementCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePath+".") && !strings.HasPrefix(frame.Function, "runtime.") && !strings.HasPrefix(frame.Function, "iter.") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func (c Repository) logger() *slog.Logger {
+	if c.DB == nil {
+		return slog.Default()
+	}
+	return c.DB.GetLogger()
+}
+
+func redactColumn(ent Entity, column string, v interface{}) interface{} {
+	if sensitiveColumns(ent)[strings.ToLower(column)] {
+		return Redact(v)
+	}
+	return v
+}
+
+func sensitiveColumns(ent Entity) map[string]bool {
+	results := make(map[string]bool)
+	for _, f := range columnFields(ent) {
+		if tagEnabled(f.Field, "sensitive") {
+			results[strings.ToLower(f.Field.Tag.Get("column"))] = true
+		}
+	}
+	return results
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type vault struct {
+	widget
+	Token string `column:"token" sensitive:"true"`
+}
+
+func (v *vault) GetTable() string {
+	return "vault"
+}
+
+func loggedDB(t *testing.T) (*fakeDriver, *DB, *bytes.Buffer) {
+	d, db := newFakeDB(t)
+	out := &bytes.Buffer{}
+	db.Use(LogInterceptor(slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})), LogOptions{}))
+	return d, db, out
+}
+
+func TestSensitiveValuesReachDriverUnwrapped(t *testing.T) {
+	d, db, out := loggedDB(t)
+	v := &vault{Token: "hunter2"}
+	if err := (Repository{DB: db}).Save(v); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range d.checked {
+		if _, ok := value.(Redacted); ok {
+			t.Fatalf("driver saw a Redacted wrapper: %#v", d.checked)
+		}
+	}
+	if d.count("hunter2") != 1 {
+		t.Fatalf("driver did not get the value: %q", d.statements())
+	}
+	if strings.Contains(out.String(), "hunter2") || !strings.Contains(out.String(), redacted) {
+		t.Fatalf("sensitive value logged: %s", out.String())
+	}
+}
+
+func TestSensitiveWhereArgsAreRedacted(t *testing.T) {
+	d, db, out := loggedDB(t)
+	var e Entity = &vault{}
+	db.Take(&e, "token", "hunter2")
+	if d.count("WHERE token = ? LIMIT 1 -- hunter2") != 1 {
+		t.Fatalf("driver did not get the value: %q", d.statements())
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Fatalf("sensitive WHERE arg logged: %s", out.String())
+	}
+}
@@ -0,0 +1,308 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Page struct {
+	Items []Entity
+	Next  string
+	Prev  string
+	Page  int
+	Size  int
+	Total int64
+}
+
+type pagination struct {
+	page   int
+	size   int
+	after  string
+	before string
+	order  []string
+	total  bool
+}
+
+func (c Repository) Paginate(page, size int) Repository {
+	if page < 1 {
+		page = 1
+	}
+	c.pagination.page = page
+	c.pagination.size = size
+	return c
+}
+
+func (c Repository) Limit(n int) Repository {
+	c.pagination.size = n
+	return c
+}
+
+func (c Repository) After(cursor string) Repository {
+	c.pagination.after = cursor
+	c.pagination.before = ""
+	return c
+}
+
+func (c Repository) Before(cursor string) Repository {
+	c.pagination.before = cursor
+	c.pagination.after = ""
+	return c
+}
+
+func (c Repository) OrderBy(columns ...string) Repository {
+	c.pagination.order = columns
+	return c
+}
+
+func (c Repository) WithTotal() Repository {
+	c.pagination.total = true
+	return c
+}
+
+func (c Repository) orderColumns(ent Entity) ([]string, bool, error) {
+	if len(c.pagination.order) == 0 {
+		return primaryKeyColumns(ent), false, nil
+	}
+	columns := make([]string, 0)
+	desc := strings.HasPrefix(c.pagination.order[0], "-")
+	for _, col := range c.pagination.order {
+		if strings.HasPrefix(col, "-") != desc {
+			return nil, false, fmt.Errorf("%s: order columns must share one direction", ent.GetTable())
+		}
+		col = strings.TrimPrefix(col, "-")
+		if !hasColumn(ent, col) {
+			return nil, false, fmt.Errorf("%s: unknown order column %q", ent.GetTable(), col)
+		}
+		columns = append(columns, col)
+	}
+	for _, key := range primaryKeyColumns(ent) {
+		if !containsFold(columns, key) {
+			columns = append(columns, key)
+		}
+	}
+	return columns, desc, nil
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func qualify(alias string, columns []string) []string {
+	results := make([]string, 0)
+	for _, col := range columns {
+		results = append(results, alias+"."+col)
+	}
+	return results
+}
+
+func orderSQL(columns []string, desc bool) string {
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	s := make([]string, 0)
+	for _, col := range columns {
+		s = append(s, col+dir)
+	}
+	return " ORDER BY " + strings.Join(s, ", ")
+}
+
+func (c Repository) pageClause(ent Entity) (string, error) {
+	if c.pagination.after != "" || c.pagination.before != "" {
+		return "", fmt.Errorf("%s: cursors need List", ent.GetTable())
+	}
+	if c.pagination.size <= 0 && len(c.pagination.order) == 0 {
+		return "", nil
+	}
+	columns, desc, err := c.orderColumns(ent)
+	if err != nil {
+		return "", err
+	}
+	q := orderSQL(qualify("t", columns), desc)
+	if c.pagination.size > 0 {
+		q += " LIMIT " + strconv.Itoa(c.pagination.size)
+		if c.pagination.page > 1 {
+			q += " OFFSET " + strconv.Itoa((c.pagination.page-1)*c.pagination.size)
+		}
+	}
+	return q, nil
+}
+
+func (c Repository) List(ent Entity, where string, args ...interface{}) (Page, error) {
+	page := Page{Page: c.pagination.page, Size: c.pagination.size, Total: -1}
+	columns, desc, err := c.orderColumns(ent)
+	if err != nil {
+		return page, err
+	}
+	lock, err := c.lockClause(ent)
+	if err != nil {
+		return page, err
+	}
+	from, err := c.selectFrom(ent)
+	if err != nil {
+		return page, err
+	}
+	base := " WHERE 1 = 1" + c.scopeClause(ent, "t")
+	if where != "" {
+		base += " AND (" + where + ")"
+	}
+	if c.pagination.total {
+		row, err := c.query(stmt(ent, OpCount, "SELECT COUNT(*) FROM "+ent.GetTable()+" t"+base, args...))
+		if err != nil {
+			return page, handleSQLError(nil, ent, "COUNT", err, "")
+		}
+		if row.Next() {
+			err = row.Scan(&page.Total)
+		}
+		err = handleSQLError(row, ent, "COUNT", err, "")
+		row.Close()
+		if err != nil {
+			return page, err
+		}
+	}
+	q := from + base
+	qargs := append(make([]interface{}, 0), args...)
+	cursor := c.pagination.after
+	backward := c.pagination.before != ""
+	if backward {
+		cursor = c.pagination.before
+	}
+	if cursor != "" {
+		values, err := DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		if len(values) != len(columns) {
+			return page, fmt.Errorf("%s: cursor has %d values for %d order columns", ent.GetTable(), len(values), len(columns))
+		}
+		op := ">"
+		if desc != backward {
+			op = "<"
+		}
+		q += fmt.Sprintf(" AND (%s) %s (%s)", strings.Join(qualify("t", columns), ", "), op, strings.Join(placeholderList(len(values)), ", "))
+		qargs = append(qargs, values...)
+	}
+	q += orderSQL(qualify("t", columns), desc != backward)
+	if page.Size > 0 {
+		limit := page.Size + 1
+		if lock != "" {
+			limit = page.Size
+		}
+		q += " LIMIT " + strconv.Itoa(limit)
+		if cursor == "" && page.Page > 1 {
+			q += " OFFSET " + strconv.Itoa((page.Page-1)*page.Size)
+		}
+	}
+	rows, err := c.query(stmt(ent, OpSelect, q+lock, qargs...))
+	if err != nil {
+		return page, handleSQLError(nil, ent, "SELECT", err, "")
+	}
+	defer rows.Close()
+	items, err := c.scanAll(rows, ent)
+	if err = handleSQLError(rows, ent, "SELECT", err, ""); err != nil {
+		return page, err
+	}
+	more := page.Size > 0 && len(items) > page.Size
+	if lock != "" {
+		more = page.Size > 0 && len(items) == page.Size
+	}
+	if more {
+		items = items[:page.Size]
+	}
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	page.Items = items
+	if len(items) == 0 {
+		return page, nil
+	}
+	if err = c.loadRelations(items); err != nil {
+		return page, err
+	}
+	hasNext := more || backward
+	hasPrev := (backward && more) || c.pagination.after != "" || (cursor == "" && page.Page > 1)
+	if hasNext {
+		if page.Next, err = EncodeCursor(items[len(items)-1], columns); err != nil {
+			return page, err
+		}
+	}
+	if hasPrev {
+		if page.Prev, err = EncodeCursor(items[0], columns); err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
+
+func NewPage(items []Entity, size int, columns []string, cursor string) (Page, error) {
+	page := Page{Items: items, Size: size, Total: -1}
+	more := size > 0 && len(items) > size
+	if more {
+		page.Items = items[:size]
+	}
+	if len(page.Items) == 0 {
+		return page, nil
+	}
+	var err error
+	if more {
+		if page.Next, err = EncodeCursor(page.Items[len(page.Items)-1], columns); err != nil {
+			return page, err
+		}
+	}
+	if cursor != "" {
+		if page.Prev, err = EncodeCursor(page.Items[0], columns); err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
+
+func EncodeCursor(ent Entity, columns []string) (string, error) {
+	values := make([]interface{}, 0)
+	for _, col := range columns {
+		v, ok := columnValue(ent, col)
+		if !ok {
+			return "", fmt.Errorf("%s: no value for cursor column %s", ent.GetTable(), col)
+		}
+		values = append(values, v)
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	values := make([]interface{}, 0)
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	for i, v := range values {
+		if n, ok := v.(json.Number); ok {
+			if iv, err := n.Int64(); err == nil {
+				values[i] = iv
+			} else if fv, err := n.Float64(); err == nil {
+				values[i] = fv
+			}
+		}
+	}
+	return values, nil
+}
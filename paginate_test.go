@@ -0,0 +1,136 @@
+package db
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestListOffsetPage(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("COUNT(*)", []string{"count"}, []driver.Value{int64(5)})
+	d.returns("LIMIT 3 OFFSET 2", widgetColumns, widgetRow("w3", "c", 0), widgetRow("w4", "d", 0), widgetRow("w5", "e", 0))
+	page, err := Repository{DB: db}.Paginate(2, 2).WithTotal().List(&widget{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 5 || len(page.Items) != 2 {
+		t.Fatalf("total %d, %d items", page.Total, len(page.Items))
+	}
+	if page.Next == "" || page.Prev == "" {
+		t.Fatalf("next %q, prev %q", page.Next, page.Prev)
+	}
+	values, err := DecodeCursor(page.Next)
+	if err != nil || len(values) != 1 || values[0] != "w4" {
+		t.Fatalf("next cursor %v, %v", values, err)
+	}
+}
+
+func TestListAfterCursor(t *testing.T) {
+	d, db := newFakeDB(t)
+	cursor, err := EncodeCursor(&widget{SoftDeleteModel: SoftDeleteModel{Model: Model{ID: "w2"}}}, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.returns("-- w2", widgetColumns, widgetRow("w3", "c", 0))
+	page, err := Repository{DB: db}.After(cursor).Limit(2).List(&widget{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Next != "" || page.Prev == "" {
+		t.Fatalf("%d items, next %q, prev %q", len(page.Items), page.Next, page.Prev)
+	}
+	q := d.statements()[0]
+	if !strings.Contains(q, "AND (t.id) > (?) ORDER BY t.id ASC LIMIT 3") {
+		t.Fatalf("unexpected query %s", q)
+	}
+}
+
+func TestListBeforeCursorReversesOrder(t *testing.T) {
+	d, db := newFakeDB(t)
+	cursor, _ := EncodeCursor(&widget{SoftDeleteModel: SoftDeleteModel{Model: Model{ID: "w5"}}}, []string{"id"})
+	d.returns("-- w5", widgetColumns, widgetRow("w4", "d", 0), widgetRow("w3", "c", 0))
+	page, err := Repository{DB: db}.Before(cursor).Limit(2).List(&widget{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(d.statements()[0], "AND (t.id) < (?) ORDER BY t.id DESC") {
+		t.Fatalf("unexpected query %s", d.statements()[0])
+	}
+	if len(page.Items) != 2 || page.Items[0].(*widget).ID != "w3" {
+		t.Fatalf("items not restored to ascending order: %v", page.Items)
+	}
+}
+
+func TestListRejectsMixedOrder(t *testing.T) {
+	_, db := newFakeDB(t)
+	if _, err := (Repository{DB: db}).OrderBy("name", "-id").List(&widget{}, ""); err == nil {
+		t.Fatal("expected an error for mixed order directions")
+	}
+}
+
+func TestListRejectsUnknownOrderColumns(t *testing.T) {
+	d, db := newFakeDB(t)
+	_, err := Repository{DB: db}.OrderBy("name; DROP TABLE widget --").Limit(3).List(&widget{}, "")
+	if err == nil || !strings.Contains(err.Error(), "unknown order column") {
+		t.Fatalf("err = %v", err)
+	}
+	if len(d.statements()) != 0 {
+		t.Fatalf("ran %v", d.statements())
+	}
+}
+
+func TestListBreaksTiesOnPrimaryKey(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("LIMIT 2", widgetColumns, widgetRow("w1", "a", 0), widgetRow("w2", "a", 0))
+	r := Repository{DB: db}.OrderBy("name").Limit(1)
+	page, err := r.List(&widget{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := DecodeCursor(page.Next)
+	if err != nil || len(values) != 2 || values[0] != "a" || values[1] != "w1" {
+		t.Fatalf("next cursor %v, %v", values, err)
+	}
+	if _, err = r.After(page.Next).List(&widget{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if q := d.statements()[1]; !strings.Contains(q, "AND (t.name, t.id) > (?, ?) ORDER BY t.name ASC, t.id ASC LIMIT 2 -- a,w1") {
+		t.Fatalf("unexpected query %s", q)
+	}
+}
+
+func TestAllRejectsCursors(t *testing.T) {
+	d, db := newFakeDB(t)
+	cursor, _ := EncodeCursor(&widget{SoftDeleteModel: SoftDeleteModel{Model: Model{ID: "w1"}}}, []string{"id"})
+	if _, err := (Repository{DB: db}).After(cursor).All([]string{"w1", "w2"}, &widget{}); err == nil {
+		t.Fatal("All dropped the cursor")
+	}
+	if _, err := (Repository{DB: db}).Before(cursor).SelectIn(&widget{}, []string{"w1"}); err == nil {
+		t.Fatal("SelectIn dropped the cursor")
+	}
+	if len(d.statements()) != 0 {
+		t.Fatalf("ran %v", d.statements())
+	}
+}
+
+func TestBuilderKeysetPage(t *testing.T) {
+	cursor, _ := EncodeCursor(&widget{Name: "a", SoftDeleteModel: SoftDeleteModel{Model: Model{ID: "w1"}}}, []string{"name", "id"})
+	db := &DB{}
+	name, id := Table{Alias: "w", Key: "name"}, Table{Alias: "w", Key: "id"}
+	q := db.Select("widget", "w", "w", []string{"id", "name"}).After(cursor, name, id).OrderBy(name, "asc").OrderBy(id, "asc").Limit(3)
+	if q.Err() != nil || !strings.HasSuffix(q.GetQuery(), "FROM widget w WHERE (w.name, w.id) > (?, ?) ORDER BY w.name ASC, w.id ASC LIMIT 3") {
+		t.Fatalf("query %q, %v", q.GetQuery(), q.Err())
+	}
+	if args := q.GetArgs(); len(args) != 2 || args[0] != "a" || args[1] != "w1" {
+		t.Fatalf("args %v", args)
+	}
+	if db.Select("widget", "w", "w", []string{"id"}).After("!", id).Err() == nil {
+		t.Fatal("bad cursor accepted")
+	}
+	items := []Entity{&widget{Name: "b"}, &widget{Name: "c"}, &widget{Name: "d"}}
+	page, err := NewPage(items, 2, []string{"name"}, cursor)
+	if err != nil || len(page.Items) != 2 || page.Next == "" || page.Prev == "" {
+		t.Fatalf("page %+v, %v", page, err)
+	}
+}
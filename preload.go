@@ -0,0 +1,257 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+func (c Repository) Preload(paths ...string) Repository {
+	c.preload = append(append(make([]string, 0), c.preload...), paths...)
+	return c
+}
+
+func (c Repository) loadRelations(ents []Entity) error {
+	if len(c.preload) == 0 || len(ents) == 0 {
+		return nil
+	}
+	return c.preloadPaths(ents, c.preload)
+}
+
+func (c Repository) preloadPaths(ents []Entity, paths []string) error {
+	names := make([]string, 0)
+	nested := make(map[string][]string)
+	for _, p := range paths {
+		name, rest, _ := strings.Cut(p, ".")
+		if _, ok := nested[name]; !ok {
+			names = append(names, name)
+			nested[name] = make([]string, 0)
+		}
+		if rest != "" {
+			nested[name] = append(nested[name], rest)
+		}
+	}
+	for _, name := range names {
+		rel, err := c.Relation(ents[0], name)
+		if err != nil {
+			return err
+		}
+		children, err := c.preloadRelation(rel, ents)
+		if err != nil {
+			return fmt.Errorf("preload %s.%s: %w", rel.Owner, name, err)
+		}
+		if len(nested[name]) > 0 && len(children) > 0 {
+			if err := c.preloadPaths(children, nested[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c Repository) preloadRelation(rel Relation, owners []Entity) ([]Entity, error) {
+	target, _ := entityForType(rel.TargetType)
+	targetKey := primaryKeyColumns(target)[0]
+	grouped := make(map[string][]Entity)
+	loaded := make([]Entity, 0)
+	switch rel.Kind {
+	case HasOne, HasMany:
+		children, err := c.selectWhereIn(target, rel.ForeignKey, ownerKeys(owners, rel.References))
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			k := keyString(relationKey(child, rel.ForeignKey))
+			grouped[k] = append(grouped[k], child)
+		}
+		for _, o := range owners {
+			rel.assign(o, grouped[keyString(relationKey(o, rel.References))])
+		}
+		loaded = children
+	case BelongsTo:
+		keys := make([]interface{}, 0)
+		for _, o := range owners {
+			keys = append(keys, belongsToKey(rel, o, targetKey))
+		}
+		parents, err := c.selectWhereIn(target, targetKey, uniqueKeys(keys))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range parents {
+			k := keyString(relationKey(p, targetKey))
+			grouped[k] = append(grouped[k], p)
+		}
+		for i, o := range owners {
+			if found, ok := grouped[keyString(keys[i])]; ok {
+				rel.assign(o, found)
+			}
+		}
+		loaded = parents
+	case ManyToMany:
+		pairs, err := c.joinPairs(rel, ownerKeys(owners, primaryKeyColumns(owners[0])[0]))
+		if err != nil {
+			return nil, err
+		}
+		targetKeys := make([]interface{}, 0)
+		for _, p := range pairs {
+			targetKeys = append(targetKeys, p[1])
+		}
+		children, err := c.selectWhereIn(target, rel.References, uniqueKeys(targetKeys))
+		if err != nil {
+			return nil, err
+		}
+		byKey := make(map[string]Entity)
+		for _, child := range children {
+			byKey[keyString(relationKey(child, rel.References))] = child
+		}
+		for _, p := range pairs {
+			if child, ok := byKey[keyString(p[1])]; ok {
+				k := keyString(p[0])
+				grouped[k] = append(grouped[k], child)
+			}
+		}
+		for _, o := range owners {
+			rel.assign(o, grouped[keyString(relationKey(o, primaryKeyColumns(o)[0]))])
+		}
+		loaded = children
+	}
+	return loaded, nil
+}
+
+func belongsToKey(rel Relation, owner Entity, targetKey string) interface{} {
+	if v, ok := columnValue(owner, rel.ForeignKey); ok {
+		if valuer, isValuer := v.(driver.Valuer); isValuer {
+			v, _ = valuer.Value()
+			return v
+		}
+		if _, isRel := relationTargetType(reflect.TypeOf(v)); !isRel {
+			return v
+		}
+	}
+	for _, t := range rel.targets(owner) {
+		return relationKey(t, targetKey)
+	}
+	return nil
+}
+
+func ownerKeys(owners []Entity, column string) []interface{} {
+	keys := make([]interface{}, 0)
+	for _, o := range owners {
+		keys = append(keys, relationKey(o, column))
+	}
+	return uniqueKeys(keys)
+}
+
+func uniqueKeys(keys []interface{}) []interface{} {
+	results := make([]interface{}, 0)
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if k == nil || reflect.ValueOf(k).IsZero() {
+			continue
+		}
+		s := keyString(k)
+		if !seen[s] {
+			seen[s] = true
+			results = append(results, k)
+		}
+	}
+	return results
+}
+
+func keyString(v interface{}) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func (c Repository) selectWhereIn(proto Entity, column string, keys []interface{}) ([]Entity, error) {
+	results := make([]Entity, 0)
+	size := c.chunkSize(1)
+	for offset := 0; offset < len(keys); offset += size {
+		end := offset + size
+		if end > len(keys) {
+			end = len(keys)
+		}
+		q := fmt.Sprintf("SELECT * FROM %s WHERE %s IN (%s)", proto.GetTable(), column, strings.Join(placeholderList(end-offset), ","))
+		args := make([]interface{}, 0)
+		for _, k := range keys[offset:end] {
+			args = append(args, redactColumn(proto, column, k))
+		}
+		rows, err := c.query(stmt(proto, OpSelect, q+c.scopeClause(proto, ""), args...))
+		if err != nil {
+			return nil, handleSQLError(nil, proto, "SELECT", err, "")
+		}
+		chunk, err := c.scanAll(rows, proto)
+		rows.Close()
+		if err != nil {
+			return nil, handleSQLError(nil, proto, "SELECT", err, "")
+		}
+		results = append(results, chunk...)
+	}
+	return results, nil
+}
+
+func (c Repository) joinPairs(rel Relation, keys []interface{}) ([][2]interface{}, error) {
+	results := make([][2]interface{}, 0)
+	size := c.chunkSize(1)
+	for offset := 0; offset < len(keys); offset += size {
+		end := offset + size
+		if end > len(keys) {
+			end = len(keys)
+		}
+		q := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s)", rel.JoinForeignKey, rel.JoinReferences, rel.JoinTable, rel.JoinForeignKey, strings.Join(placeholderList(end-offset), ","))
+		rows, err := c.query(tableStmt(rel.JoinTable, OpSelect, q, keys[offset:end]...))
+		if err != nil {
+			return nil, sqlError(rel.JoinTable, "SELECT", "", err)
+		}
+		for rows.Next() {
+			var pair [2]interface{}
+			if err := rows.Scan(&pair[0], &pair[1]); err != nil {
+				rows.Close()
+				return nil, sqlError(rel.JoinTable, "SELECT", "", err)
+			}
+			results = append(results, pair)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, sqlError(rel.JoinTable, "SELECT", "", err)
+		}
+	}
+	return results, nil
+}
+
+func (r Relation) assign(owner Entity, children []Entity) {
+	v := r.field(owner)
+	if !v.CanSet() {
+		return
+	}
+	if h, ok := v.Addr().Interface().(relationHandle); ok {
+		h.setLoaded(children)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 0, len(children))
+		for _, child := range children {
+			s = reflect.Append(s, entityValue(child, v.Type().Elem()))
+		}
+		v.Set(s)
+	case reflect.Ptr, reflect.Struct:
+		if len(children) == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return
+		}
+		v.Set(entityValue(children[0], v.Type()))
+	}
+}
+
+func entityValue(ent Entity, t reflect.Type) reflect.Value {
+	v := reflect.ValueOf(ent)
+	if t.Kind() != reflect.Ptr && v.Kind() == reflect.Ptr {
+		return v.Elem()
+	}
+	return v
+}
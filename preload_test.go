@@ -0,0 +1,62 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreloadHasManyInOneQuery(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("FROM gizmo", widgetColumns, widgetRow("g1", "a", 0), widgetRow("g2", "b", 0))
+	d.returns("FROM part WHERE gizmo_id IN (?,?)", partColumns, partRow("p1", "g1"), partRow("p2", "g1"), partRow("p3", "g2"))
+	page, err := Repository{DB: db}.Preload("Parts").List(&gizmo{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(d.statements()); n != 2 {
+		t.Fatalf("expected 2 statements, got %d: %q", n, d.statements())
+	}
+	g1, g2 := page.Items[0].(*gizmo), page.Items[1].(*gizmo)
+	if len(g1.Parts) != 2 || len(g2.Parts) != 1 || g2.Parts[0].ID != "p3" {
+		t.Fatalf("parts not stitched: %d, %d", len(g1.Parts), len(g2.Parts))
+	}
+}
+
+func TestPreloadChunksKeys(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("FROM gizmo", widgetColumns, widgetRow("g1", "a", 0), widgetRow("g2", "b", 0), widgetRow("g3", "c", 0))
+	if _, err := (Repository{DB: db}).WithBatchSize(2).Preload("Parts").List(&gizmo{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.count("FROM part WHERE gizmo_id IN"); n != 2 {
+		t.Fatalf("expected 2 IN queries, got %d", n)
+	}
+}
+
+func TestPreloadUnknownRelation(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("FROM gizmo", widgetColumns, widgetRow("g1", "a", 0))
+	if _, err := (Repository{DB: db}).Preload("Nope").List(&gizmo{}, ""); err == nil {
+		t.Fatal("expected an error for an unknown relation")
+	}
+}
+
+func TestPreloadBelongsToFromQueriedRows(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("FROM gizmo", gizmoColumns, gizmoRow("g1", "m1"), gizmoRow("g2", "m1"), gizmoRow("g3", nil))
+	d.returns("FROM maker WHERE id IN (?)", widgetColumns, widgetRow("m1", "acme", 0))
+	page, err := Repository{DB: db}.Preload("Maker").List(&gizmo{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.statements(); len(got) != 2 || !strings.HasSuffix(got[1], "-- m1") {
+		t.Fatalf("expected one maker query, got %q", d.statements())
+	}
+	g1, g2, g3 := page.Items[0].(*gizmo), page.Items[1].(*gizmo), page.Items[2].(*gizmo)
+	if g1.Maker == nil || g1.Maker.Name != "acme" || g2.Maker != g1.Maker {
+		t.Fatalf("maker not stitched: %+v, %+v", g1.Maker, g2.Maker)
+	}
+	if g3.Maker != nil {
+		t.Fatalf("expected no maker for a NULL key, got %+v", g3.Maker)
+	}
+}
@@ -0,0 +1,569 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type RelationKind string
+
+const (
+	HasOne     RelationKind = "hasOne"
+	HasMany    RelationKind = "hasMany"
+	BelongsTo  RelationKind = "belongsTo"
+	ManyToMany RelationKind = "manyToMany"
+)
+
+type Relation struct {
+	Name           string
+	Kind           RelationKind
+	Owner          string
+	OwnerType      reflect.Type
+	Target         string
+	TargetType     reflect.Type
+	ForeignKey     string
+	References     string
+	JoinTable      string
+	JoinForeignKey string
+	JoinReferences string
+	JoinColumns    []JoinColumn
+	Cascade        string
+	index          []int
+}
+
+type JoinColumn struct {
+	Name       string
+	Definition string
+}
+
+func (r Relation) OwnsForeignKey() bool {
+	return r.Kind == BelongsTo
+}
+
+func (r Relation) Many() bool {
+	return r.Kind == HasMany || r.Kind == ManyToMany
+}
+
+type RelationRegistry struct {
+	mu     sync.RWMutex
+	byType map[reflect.Type][]Relation
+}
+
+func NewRelationRegistry() *RelationRegistry {
+	return &RelationRegistry{byType: make(map[reflect.Type][]Relation)}
+}
+
+func (r *RelationRegistry) Register(ent Entity) ([]Relation, error) {
+	t := entityType(ent)
+	r.mu.RLock()
+	rels, ok := r.byType[t]
+	r.mu.RUnlock()
+	if ok {
+		return rels, nil
+	}
+	rels, err := parseRelations(t)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.byType[t] = rels
+	r.mu.Unlock()
+	return rels, nil
+}
+
+func (r *RelationRegistry) Get(ent Entity) ([]Relation, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rels, ok := r.byType[entityType(ent)]
+	return rels, ok
+}
+
+func (r *RelationRegistry) All() []Relation {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	results := make([]Relation, 0)
+	for _, rels := range r.byType {
+		results = append(results, rels...)
+	}
+	return results
+}
+
+func (c Repository) Relations(ent Entity) ([]Relation, error) {
+	if c.relations != nil {
+		if rels, ok := c.relations.Get(ent); ok {
+			return rels, nil
+		}
+	}
+	return parseRelations(entityType(ent))
+}
+
+func (c Repository) Relation(ent Entity, name string) (Relation, error) {
+	rels, err := c.Relations(ent)
+	if err != nil {
+		return Relation{}, err
+	}
+	for _, r := range rels {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return Relation{}, fmt.Errorf("%s: no relation %s", ent.GetTable(), name)
+}
+
+func (c Repository) relationBetween(parent Entity, child Entity) (Relation, bool) {
+	rels, err := c.Relations(parent)
+	if err != nil {
+		return Relation{}, false
+	}
+	for _, r := range rels {
+		if r.TargetType == entityType(child) {
+			return r, true
+		}
+	}
+	return Relation{}, false
+}
+
+func entityType(ent interface{}) reflect.Type {
+	t := reflect.TypeOf(ent)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func entityForType(t reflect.Type) (Entity, bool) {
+	e, ok := reflect.New(t).Interface().(Entity)
+	return e, ok
+}
+
+func relationTargetType(t reflect.Type) (reflect.Type, bool) {
+	if h, ok := reflect.New(t).Interface().(relationHandle); ok {
+		return h.relationTarget(), true
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	if _, ok := entityForType(t); !ok {
+		return nil, false
+	}
+	return t, true
+}
+
+func parseRelations(t reflect.Type) ([]Relation, error) {
+	owner, ok := entityForType(t)
+	if !ok {
+		return nil, fmt.Errorf("%s does not implement Entity", t)
+	}
+	rels := make([]Relation, 0)
+	err := appendRelations(&rels, owner, t, nil)
+	return rels, err
+}
+
+func appendRelations(rels *[]Relation, owner Entity, t reflect.Type, index []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append(make([]int, 0), index...), i)
+		if _, skip := field.Tag.Lookup("dbskip"); skip {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := appendRelations(rels, owner, field.Type, fieldIndex); err != nil {
+				return err
+			}
+			continue
+		}
+		target, ok := relationTargetType(field.Type)
+		if !ok {
+			continue
+		}
+		rel, err := newRelation(owner, field, target)
+		if err != nil {
+			return err
+		}
+		rel.index = fieldIndex
+		*rels = append(*rels, rel)
+	}
+	return nil
+}
+
+func newRelation(owner Entity, field reflect.StructField, target reflect.Type) (Relation, error) {
+	targetEnt, _ := entityForType(target)
+	ownerType := entityType(owner)
+	rel := Relation{
+		Name:       field.Name,
+		Owner:      owner.GetTable(),
+		OwnerType:  ownerType,
+		Target:     targetEnt.GetTable(),
+		TargetType: target,
+	}
+	kind, explicit := field.Tag.Lookup("relation")
+	_, join := field.Tag.Lookup("join")
+	many := field.Type.Kind() == reflect.Slice || field.Type.Kind() == reflect.Array
+	if h, ok := reflect.New(field.Type).Interface().(relationHandle); ok {
+		many = h.relationMany()
+	}
+	switch {
+	case explicit:
+		rel.Kind = RelationKind(kind)
+	case join:
+		rel.Kind = ManyToMany
+	case many:
+		rel.Kind = HasMany
+	default:
+		rel.Kind = BelongsTo
+	}
+	switch rel.Kind {
+	case BelongsTo:
+		if references, ok := field.Tag.Lookup("references"); ok {
+			rel.Target = references
+		}
+		rel.ForeignKey = field.Tag.Get("column")
+		if foreignKey := field.Tag.Get("foreignKey"); rel.ForeignKey == "" && foreignKey != "true" {
+			rel.ForeignKey = foreignKey
+		}
+		if rel.ForeignKey == "" {
+			rel.ForeignKey = CamelToSnake(field.Name) + "_id"
+		}
+		rel.References = primaryKeyColumns(targetEnt)[0]
+	case HasOne, HasMany:
+		rel.ForeignKey = field.Tag.Get("foreignKey")
+		if rel.ForeignKey == "" || rel.ForeignKey == "true" {
+			rel.ForeignKey = CamelToSnake(ownerType.Name()) + "_id"
+		}
+		rel.References = primaryKeyColumns(owner)[0]
+	case ManyToMany:
+		rel.References = primaryKeyColumns(targetEnt)[0]
+		rel.JoinForeignKey = CamelToSnake(ownerType.Name()) + "_id"
+		rel.JoinReferences = CamelToSnake(target.Name()) + "_id"
+		rel.JoinTable = CamelToSnake(ownerType.Name() + "_" + target.Name())
+		if joinString, ok := field.Tag.Lookup("join"); ok {
+			first := strings.Split(joinString, ",")
+			if len(first) != 2 {
+				return rel, fmt.Errorf("%s.%s: join must be table_1:key,table_2:key", ownerType.Name(), field.Name)
+			}
+			t1 := strings.Split(first[0], ":")
+			t2 := strings.Split(first[1], ":")
+			if len(t1) != 2 || len(t2) != 2 {
+				return rel, fmt.Errorf("%s.%s: join must be table_1:key,table_2:key", ownerType.Name(), field.Name)
+			}
+			rel.JoinTable = CamelToSnake(fmt.Sprintf("%s_%s", t1[0], t2[0]))
+			if t2[0] == rel.Owner || t2[0] == CamelToSnake(ownerType.Name()) {
+				t1, t2 = t2, t1
+			}
+			rel.JoinForeignKey = t1[1]
+			rel.JoinReferences = t2[1]
+		}
+		if tableName, ok := field.Tag.Lookup("tableName"); ok {
+			rel.JoinTable = tableName
+		}
+		if joinColumns, ok := field.Tag.Lookup("joinColumns"); ok {
+			for _, def := range splitDefinitions(joinColumns) {
+				name, definition, _ := strings.Cut(strings.TrimSpace(def), " ")
+				if name == "" || strings.TrimSpace(definition) == "" {
+					return rel, fmt.Errorf("%s.%s: joinColumns must be name type,name type", ownerType.Name(), field.Name)
+				}
+				rel.JoinColumns = append(rel.JoinColumns, JoinColumn{Name: name, Definition: strings.TrimSpace(definition)})
+			}
+		}
+	default:
+		return rel, fmt.Errorf("%s.%s: unknown relation %q", ownerType.Name(), field.Name, kind)
+	}
+	if cascade, ok := field.Tag.Lookup("cascade"); ok {
+		if cascade != CascadeDelete && cascade != CascadeDetach {
+			return rel, fmt.Errorf("%s.%s: cascade must be %s or %s", ownerType.Name(), field.Name, CascadeDelete, CascadeDetach)
+		}
+		if rel.Kind == BelongsTo {
+			return rel, fmt.Errorf("%s.%s: cascade is not supported on %s relations", ownerType.Name(), field.Name, BelongsTo)
+		}
+		rel.Cascade = cascade
+	}
+	return rel, nil
+}
+
+func splitDefinitions(s string) []string {
+	results := make([]string, 0)
+	depth := 0
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			results = append(results, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(results, s[start:])
+}
+
+func (r Relation) field(owner interface{}) reflect.Value {
+	return reflect.Indirect(reflect.ValueOf(owner)).FieldByIndex(r.index)
+}
+
+func (r Relation) targets(owner interface{}) []Entity {
+	results := make([]Entity, 0)
+	v := r.field(owner)
+	if v.CanAddr() {
+		if h, ok := v.Addr().Interface().(relationHandle); ok {
+			return h.loadedEntities()
+		}
+	}
+	appendTarget := func(v reflect.Value) {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return
+			}
+			results = append(results, v.Interface().(Entity))
+			return
+		}
+		if v.CanAddr() {
+			results = append(results, v.Addr().Interface().(Entity))
+		}
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			appendTarget(v.Index(i))
+		}
+		return results
+	}
+	appendTarget(v)
+	return results
+}
+
+func setColumn(ent interface{}, column string, value interface{}) bool {
+	for _, f := range columnFields(ent) {
+		if !strings.EqualFold(f.Field.Tag.Get("column"), column) || !f.Value.CanSet() {
+			continue
+		}
+		v := reflect.ValueOf(value)
+		if !v.IsValid() {
+			f.Value.Set(reflect.Zero(f.Value.Type()))
+			return true
+		}
+		if v.Type().ConvertibleTo(f.Value.Type()) {
+			f.Value.Set(v.Convert(f.Value.Type()))
+			return true
+		}
+	}
+	return false
+}
+
+func (c Repository) registry() *RelationRegistry {
+	if c.relations != nil {
+		return c.relations
+	}
+	registry := NewRelationRegistry()
+	for _, e := range c.Tables {
+		registry.Register(e)
+	}
+	return registry
+}
+
+func (c Repository) relationDDL() ([]JoinTable, []Alters) {
+	joins := make([]JoinTable, 0)
+	alters := make([]Alters, 0)
+	seen := make(map[string]bool)
+	for _, rel := range c.registry().All() {
+		switch rel.Kind {
+		case ManyToMany:
+			if seen[rel.JoinTable] {
+				continue
+			}
+			seen[rel.JoinTable] = true
+			jt := JoinTable{
+				FirstTable:  rel.Owner,
+				FirstKey:    rel.JoinForeignKey,
+				SecondTable: rel.Target,
+				SecondKey:   rel.JoinReferences,
+			}
+			payload := ""
+			for _, jc := range rel.JoinColumns {
+				payload += ", " + jc.Name + " " + jc.Definition
+			}
+			owner, _ := entityForType(rel.OwnerType)
+			target, _ := entityForType(rel.TargetType)
+			jt.SQL = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s, %s%s, PRIMARY KEY (%s, %s))", rel.JoinTable, keyColumnSQL(jt.FirstKey, keyType(owner)), keyColumnSQL(jt.SecondKey, keyType(target)), payload, jt.FirstKey, jt.SecondKey)
+			joins = append(joins, jt)
+			for _, at := range []Alters{
+				{Table: rel.JoinTable, Reference: rel.Owner, ForeignKey: rel.JoinForeignKey},
+				{Table: rel.JoinTable, Reference: rel.Target, ForeignKey: rel.JoinReferences},
+			} {
+				at.GenerateSQL(at.Table)
+				alters = append(alters, at)
+			}
+		case HasOne, HasMany:
+			key := rel.Target + "." + rel.ForeignKey
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			at := Alters{Table: rel.Target, Reference: rel.Owner, ForeignKey: rel.ForeignKey}
+			at.GenerateSQL(at.Table)
+			alters = append(alters, at)
+		}
+	}
+	return joins, alters
+}
+
+func (c Repository) inlineJoin(attribute Column) (string, JoinTable, []Alters, error) {
+	first := strings.Split(attribute.JoinString, ",")
+	if len(first) != 2 {
+		return "", JoinTable{}, nil, fmt.Errorf("%s: join must be table_1:key,table_2:key", attribute.AttributeName)
+	}
+	t1 := strings.Split(first[0], ":")
+	t2 := strings.Split(first[1], ":")
+	if len(t1) != 2 || len(t2) != 2 {
+		return "", JoinTable{}, nil, fmt.Errorf("%s: join must be table_1:key,table_2:key", attribute.AttributeName)
+	}
+	jt := JoinTable{
+		FirstTable:  t1[0],
+		FirstKey:    t1[1],
+		SecondTable: t2[0],
+		SecondKey:   t2[1],
+	}
+	table := attribute.TableName
+	if table == "" {
+		table = CamelToSnake(fmt.Sprintf("%s_%s", jt.FirstTable, jt.SecondTable))
+	}
+	jt.SQL = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s, %s)", table, keyColumnSQL(jt.FirstKey, c.tableKeyType(jt.FirstTable)), keyColumnSQL(jt.SecondKey, c.tableKeyType(jt.SecondTable)))
+	alters := []Alters{
+		{Table: table, Reference: jt.FirstTable, ForeignKey: jt.FirstKey},
+		{Table: table, Reference: jt.SecondTable, ForeignKey: jt.SecondKey},
+	}
+	for i := range alters {
+		alters[i].GenerateSQL(table)
+	}
+	return table, jt, alters, nil
+}
+
+func (c Repository) joinTableNames() map[string]bool {
+	results := make(map[string]bool)
+	for _, rel := range c.registry().All() {
+		if rel.Kind == ManyToMany {
+			results[rel.JoinTable] = true
+		}
+	}
+	return results
+}
+
+func (c Repository) tableKeyType(table string) string {
+	for _, e := range c.Tables {
+		if e.GetTable() == table || CamelToSnake(entityType(e).Name()) == CamelToSnake(table) {
+			return keyType(e)
+		}
+	}
+	return "varchar(36)"
+}
+
+func foreignKeyType(t reflect.Type) string {
+	target, ok := relationTargetType(t)
+	if !ok {
+		return "varchar(36)"
+	}
+	ent, _ := entityForType(target)
+	return keyType(ent)
+}
+
+func keyType(ent Entity) string {
+	for _, f := range columnFields(ent) {
+		if !tagEnabled(f.Field, "primaryKey") {
+			continue
+		}
+		kind := f.Field.Type.Kind()
+		if kind == reflect.Ptr {
+			kind = f.Field.Type.Elem().Kind()
+		}
+		generate := f.Field.Tag.Get("generate")
+		if generate == GenerateAutoIncrement {
+			if s, ok := autoIncrementType(kind); ok {
+				return s
+			}
+		} else if generate != "" {
+			return generatedType(generate, "varchar(36)")
+		}
+		if datatype := f.Field.Tag.Get("datatype"); datatype == "uuid.UUID" {
+			return "varchar(36)"
+		} else if datatype != "" {
+			return datatype
+		}
+		if s, ok := autoIncrementType(kind); ok {
+			return s
+		}
+		return "varchar(255)"
+	}
+	return "varchar(36)"
+}
+
+func keyColumnSQL(name string, typeString string) string {
+	column := Column{ColumnString: name, TypeString: typeString, NullString: "not null"}
+	column.GenerateSQL()
+	return strings.TrimSpace(column.SQLDefinition)
+}
+
+func relationKey(ent Entity, column string) interface{} {
+	v, _ := columnValue(ent, column)
+	return v
+}
+
+func (c Repository) linkRows(table string, columns []string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	insert := "INSERT INTO "
+	suffix := " ON CONFLICT DO NOTHING"
+	if c.DB.GetDialect() == DialectMySQL {
+		insert = "INSERT IGNORE INTO "
+		suffix = ""
+	}
+	if update := columns[2:]; len(update) > 0 {
+		insert = "INSERT INTO "
+		sets := make([]string, 0)
+		for _, col := range update {
+			if c.DB.GetDialect() == DialectMySQL {
+				sets = append(sets, col+" = VALUES("+col+")")
+			} else {
+				sets = append(sets, col+" = excluded."+col)
+			}
+		}
+		suffix = " ON CONFLICT (" + strings.Join(columns[:2], ", ") + ") DO UPDATE SET " + strings.Join(sets, ", ")
+		if c.DB.GetDialect() == DialectMySQL {
+			suffix = " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+		}
+	}
+	row := "(" + strings.Join(placeholderList(len(columns)), ",") + ")"
+	size := c.chunkSize(len(columns))
+	for offset := 0; offset < len(rows); offset += size {
+		end := offset + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+		values := make([]interface{}, 0)
+		placeholders := make([]string, 0)
+		for _, r := range rows[offset:end] {
+			values = append(values, r...)
+			placeholders = append(placeholders, row)
+		}
+		q := insert + table + " (" + strings.Join(columns, ",") + ") VALUES " + strings.Join(placeholders, ",") + suffix
+		if _, err := c.exec(tableStmt(table, OpInsert, q, values...)); err != nil {
+			return sqlError(table, "INSERT", "", err)
+		}
+	}
+	return nil
+}
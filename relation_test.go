@@ -0,0 +1,204 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type account struct {
+	widget `dbskip:"true"`
+	Key    int64 `column:"key" primaryKey:"true" generate:"autoincrement"`
+}
+
+func (a *account) GetTable() string {
+	return "account"
+}
+
+type post struct {
+	widget `dbskip:"true"`
+	ID     string   `column:"id" datatype:"uuid.UUID" primaryKey:"true"`
+	Owner  *account `column:"owner_id" references:"account"`
+	Tags   []string `column:"tags" join:"post:post_id,tag:tag_id"`
+}
+
+func (p *post) GetTable() string {
+	return "post"
+}
+
+func createTables(t *testing.T) (*fakeDriver, error) {
+	d, db := newFakeDB(t)
+	repo := Repository{DB: db}
+	repo.RegisterTable(&account{}, &post{})
+	return d, repo.CreateTables()
+}
+
+func findStatement(d *fakeDriver, match string) string {
+	for _, s := range d.statements() {
+		if strings.Contains(s, match) {
+			return s
+		}
+	}
+	return ""
+}
+
+func TestCreateTablesInlineJoin(t *testing.T) {
+	d, err := createTables(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE TABLE IF NOT EXISTS post_tag (post_id varchar(36) NOT NULL, tag_id varchar(36) NOT NULL)"
+	if findStatement(d, want) == "" {
+		t.Fatalf("missing join table DDL in %q", d.statements())
+	}
+}
+
+func TestForeignKeyUsesTargetKeyType(t *testing.T) {
+	d, err := createTables(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := findStatement(d, "CREATE TABLE IF NOT EXISTS post ")
+	if !strings.Contains(q, "owner_id BIGINT") {
+		t.Fatalf("owner_id is not BIGINT in %q", q)
+	}
+}
+
+func TestCreateTablesReturnsErrors(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.failOn("CREATE TABLE IF NOT EXISTS account", errors.New("boom"), 0)
+	repo := Repository{DB: db}
+	repo.RegisterTable(&account{}, &post{})
+	if err := repo.CreateTables(); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected the DDL error, got %v", err)
+	}
+}
+
+type crate struct {
+	widget
+	Makers []*maker `join:"crate:crate_id,maker:maker_id" joinColumns:"price decimal(10,2) not null,note varchar(20) default 'a,b'"`
+}
+
+func (c *crate) GetTable() string {
+	return "crate"
+}
+
+func TestJoinColumnsKeepParenthesesAndQuotes(t *testing.T) {
+	rel, err := Repository{}.Relation(&crate{}, "Makers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []JoinColumn{
+		{Name: "price", Definition: "decimal(10,2) not null"},
+		{Name: "note", Definition: "varchar(20) default 'a,b'"},
+	}
+	if len(rel.JoinColumns) != len(want) {
+		t.Fatalf("got %+v", rel.JoinColumns)
+	}
+	for i := range want {
+		if rel.JoinColumns[i] != want[i] {
+			t.Fatalf("column %d: got %+v, want %+v", i, rel.JoinColumns[i], want[i])
+		}
+	}
+}
+
+var gizmoColumns = append(append(make([]string, 0), widgetColumns...), "maker_id")
+
+func gizmoRow(id string, maker interface{}) []driver.Value {
+	return append(widgetRow(id, id, 0), maker)
+}
+
+func TestScanKeepsBelongsToKey(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("FROM gizmo", gizmoColumns, gizmoRow("g1", "m1"))
+	r := Repository{DB: db}
+	found, err := r.Select(&gizmo{}, "g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := found[0].(*gizmo)
+	if g.Maker == nil || g.Maker.ID != "m1" {
+		t.Fatalf("maker key lost: %+v", g.Maker)
+	}
+	d.reset()
+	if err := r.Save(g); err != nil {
+		t.Fatal(err)
+	}
+	q := findStatement(d, "INSERT INTO gizmo")
+	if !strings.Contains(q, "maker_id = COALESCE(VALUES(maker_id), maker_id)") || !strings.HasSuffix(q, ",m1") {
+		t.Fatalf("save ran %s", q)
+	}
+}
+
+type refB struct {
+	widget
+}
+
+func (r *refB) GetTable() string {
+	return "ref_b"
+}
+
+type refA struct {
+	widget
+	Maker *maker `column:"maker_id" references:"maker"`
+	B     *refB  `column:"b_id" references:"ref_b"`
+}
+
+func (r *refA) GetTable() string {
+	return "ref_a"
+}
+
+type refC struct {
+	widget
+	Maker *maker `column:"maker_id" references:"maker"`
+}
+
+func (r *refC) GetTable() string {
+	return "ref_c"
+}
+
+type badCascade struct {
+	widget
+	Maker *maker `column:"maker_id" references:"maker" cascade:"delete"`
+}
+
+func (b *badCascade) GetTable() string {
+	return "bad_cascade"
+}
+
+func TestCreateTablesKeepsForeignKeysOnTheirTable(t *testing.T) {
+	d, db := newFakeDB(t)
+	repo := Repository{DB: db}
+	repo.RegisterTable(&maker{}, &refB{}, &refA{}, &refC{})
+	if err := repo.CreateTables(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"ALTER TABLE `ref_a` ADD CONSTRAINT `fk_ref_a_maker_id` FOREIGN KEY (`maker_id`) REFERENCES `maker`",
+		"ALTER TABLE `ref_a` ADD CONSTRAINT `fk_ref_a_b_id` FOREIGN KEY (`b_id`) REFERENCES `ref_b`",
+		"ALTER TABLE `ref_c` ADD CONSTRAINT `fk_ref_c_maker_id` FOREIGN KEY (`maker_id`) REFERENCES `maker`",
+	} {
+		if d.count(want) != 1 {
+			t.Fatalf("missing %s in %v", want, d.statements())
+		}
+	}
+	if n := d.count("ALTER TABLE"); n != 3 {
+		t.Fatalf("ran %d alters: %v", n, d.statements())
+	}
+}
+
+func TestCreateTablesReturnsRelationErrors(t *testing.T) {
+	d, db := newFakeDB(t)
+	db.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	repo := Repository{DB: db}
+	repo.RegisterTable(&maker{}, &badCascade{})
+	if err := repo.CreateTables(); err == nil || !strings.Contains(err.Error(), "cascade is not supported") {
+		t.Fatalf("expected the relation error, got %v", err)
+	}
+	if len(d.statements()) != 0 {
+		t.Fatalf("ran %v", d.statements())
+	}
+}
@@ -1,16 +1,21 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
-const SqlUuid string = "varchar(35) not null"
-const SqlUuidPk string = "varchar(35) not null primary key"
+const SqlUuid string = "varchar(36) not null"
+const SqlUuidPk string = "varchar(36) not null primary key"
 const SqlString string = "varchar(255) not null"
 const SqlLong string = "text"
 const SqlInt string = "int not null default 0"
@@ -18,6 +23,27 @@ const SqlInt string = "int not null default 0"
 type Repository struct {
 	DB     *DB
 	Tables []Entity
+	Clock  func() time.Time
+
+	deleted    deletedScope
+	ctx        context.Context
+	tx         *sql.Tx
+	lock       LockMode
+	lockWait   LockWait
+	upsert     Upsert
+	batchSize  int
+	pagination pagination
+	relations  *RelationRegistry
+	preload    []string
+	joins      []string
+	joinValues []KVP
+	tracker    *Tracker
+	identity   *IdentityMap
+	noCache    bool
+	written    *writtenTables
+	undo       *undoLog
+	retry      *RetryPolicy
+	attempt    int
 }
 
 type KVP struct {
@@ -65,142 +91,258 @@ type IJoinTable interface {
 }
 
 func (c Repository) Select(ent Entity, id string) ([]Entity, error) {
-	results := make([]Entity, 0)
-	rows, err := c.DB.Conn.Query("SELECT * FROM "+ent.GetTable()+" WHERE ID = ?", id)
-	defer rows.Close()
+	key, ttl, cacheable := c.cacheKey(ent, "select", []string{id})
+	if cacheable {
+		if results, ok := c.cacheGet(ent, key); ok {
+			return results, c.loadRelations(results)
+		}
+	}
+	lock, err := c.lockClause(ent)
+	if err != nil {
+		return nil, err
+	}
+	from, err := c.selectFrom(ent)
 	if err != nil {
-		return nil, fmt.Errorf(ent.GetTable()+" %q: %v", id, err)
+		return nil, err
+	}
+	rows, err := c.query(stmt(ent, OpSelect, from+" WHERE t.ID = ?"+c.scopeClause(ent, "t")+lock, id))
+	if err != nil {
+		return nil, handleSQLError(nil, ent, "SELECT", err, id)
 	}
-	err = ent.Scan(rows, results)
+	defer rows.Close()
+	results, err := c.scanAll(rows, ent)
 	if err = handleSQLError(rows, ent, "SELECT", err, id); err != nil {
 		return nil, err
 	}
-	return results, nil
+	if cacheable {
+		c.cacheSet(key, ttl, results)
+	}
+	return results, c.loadRelations(results)
 }
 
 func (c Repository) SelectIn(ent Entity, ids []string) ([]Entity, error) {
-	results := make([]Entity, 0)
-	placeholders := make([]string, 0)
-	for i := 0; i < len(ids); i++ {
-		placeholders = append(placeholders, "?")
+	if len(ids) == 0 {
+		return make([]Entity, 0), nil
 	}
-	rows, err := c.DB.Conn.Query(fmt.Sprintf("SELECT * FROM %s WHERE ID IN (%s)", ent.GetTable(), strings.Join(placeholders, ",")), ids)
-	defer rows.Close()
+	key, ttl, cacheable := c.cacheKey(ent, "in", ids)
+	if cacheable {
+		if results, ok := c.cacheGet(ent, key); ok {
+			return results, c.loadRelations(results)
+		}
+	}
+	placeholders := placeholderList(len(ids))
+	lock, err := c.lockClause(ent)
+	if err != nil {
+		return nil, err
+	}
+	page, err := c.pageClause(ent)
+	if err != nil {
+		return nil, err
+	}
+	from, err := c.selectFrom(ent)
 	if err != nil {
 		return nil, err
 	}
-	err = ent.Scan(rows, results)
+	rows, err := c.query(stmt(ent, OpSelect, fmt.Sprintf("%s WHERE t.ID IN (%s)", from, strings.Join(placeholders, ","))+c.scopeClause(ent, "t")+page+lock, stringArgs(ids)...))
 	if err != nil {
 		return nil, err
 	}
-	return results, nil
+	defer rows.Close()
+	results, err := c.scanAll(rows, ent)
+	if err = handleSQLError(rows, ent, "SELECT", err, ""); err != nil {
+		return nil, err
+	}
+	if cacheable {
+		c.cacheSet(key, ttl, results)
+	}
+	return results, c.loadRelations(results)
 }
 
 func (c Repository) Take(result Entity, id string) error {
-	rows, err := c.DB.Conn.Query("SELECT * FROM "+result.GetTable()+" WHERE ID = ? LIMIT 1", id)
+	if c.identity != nil {
+		if existing, ok := c.identity.Get(result.GetTable(), id); ok {
+			if existing != result {
+				return loadedError(result, id)
+			}
+			return nil
+		}
+	}
+	key, ttl, cacheable := c.cacheKey(result, "select", []string{id})
+	if cacheable {
+		if rows, ok := c.cacheRows(key); ok && len(rows) > 0 && c.fromCache(result, rows[0]) == nil {
+			if c.remember(result) != result {
+				return loadedError(result, id)
+			}
+			return c.loadRelations([]Entity{result})
+		}
+	}
+	lock, err := c.lockClause(result)
+	if err != nil {
+		return err
+	}
+	from, err := c.selectFrom(result)
+	if err != nil {
+		return err
+	}
+	rows, err := c.query(stmt(result, OpSelect, from+" WHERE t.ID = ?"+c.scopeClause(result, "t")+" LIMIT 1"+lock, id))
 	if err != nil {
-		return fmt.Errorf(result.GetTable()+" %q: %v", id, err)
+		return handleSQLError(nil, result, "SELECT", err, id)
 	}
 	defer rows.Close()
-	results := make([]Entity, 1)
-	err = result.Scan(rows, results)
+	if rows.Next() {
+		if err = c.scanInto(rows, result); err == nil && c.remember(result) != result {
+			return loadedError(result, id)
+		}
+	} else if err = rows.Err(); err == nil {
+		err = sql.ErrNoRows
+	}
 	if err = handleSQLError(rows, result, "SELECT", err, id); err != nil {
 		return err
 	}
-	return nil
+	if cacheable {
+		c.cacheSet(key, ttl, []Entity{result})
+	}
+	return c.loadRelations([]Entity{result})
 }
 
 func (c Repository) Find(ent Entity) ([]Entity, error) {
-	ret := make([]Entity, 0)
 	id, err := ent.GetID()
 	if err != nil {
 		return nil, err
 	}
-	rows, err := c.DB.Conn.Query(selectQuery(ent.GetTable()), id)
+	lock, err := c.lockClause(ent)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		if err := ent.Scan(rows, ret); err != nil {
-			return nil, fmt.Errorf("scan %v", err)
-		}
-		ret = append(ret, ret...)
+	from, err := c.selectFrom(ent)
+	if err != nil {
+		return nil, err
 	}
-	if err := rows.Err(); err != nil {
+	rows, err := c.query(stmt(ent, OpSelect, from+" WHERE t.id = ?"+c.scopeClause(ent, "t")+lock, id))
+	if err != nil {
 		return nil, err
 	}
-	return ret, nil
+	defer rows.Close()
+	ret, err := c.scanAll(rows, ent)
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+	return ret, c.loadRelations(ret)
 }
 
 func (c Repository) All(ids []string, ent Entity) ([]Entity, error) {
-	results := make([]Entity, 0)
-	placeholders := make([]string, 0)
-	for _, v := range ids {
-		placeholders = append(placeholders, v)
-	}
-	row, err := c.DB.Conn.Query(`SELECT * FROM %s t WHERE t.id IN (%s)`,
-		ent.GetTable(), strings.Join(placeholders, ","), strings.Join(ids, ","),
-	)
+	if len(ids) == 0 {
+		return make([]Entity, 0), nil
+	}
+	placeholders := placeholderList(len(ids))
+	lock, err := c.lockClause(ent)
 	if err != nil {
 		return nil, err
 	}
-	defer row.Close()
-	for row.Next() {
-		var result Entity
-		if err := result.Scan(row, results); err != nil {
-			return nil, fmt.Errorf("scan %v", err)
-		}
-		results = append(results, result)
+	page, err := c.pageClause(ent)
+	if err != nil {
+		return nil, err
 	}
-	if err := row.Err(); err != nil {
+	from, err := c.selectFrom(ent)
+	if err != nil {
 		return nil, err
 	}
-	return results, nil
+	row, err := c.query(stmt(ent, OpSelect, fmt.Sprintf("%s WHERE t.id IN (%s)", from, strings.Join(placeholders, ","))+c.scopeClause(ent, "t")+page+lock, stringArgs(ids)...))
+	if err != nil {
+		return nil, err
+	}
+	defer row.Close()
+	results, err := c.scanAll(row, ent)
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+	return results, c.loadRelations(results)
 }
 
 func (c Repository) Save(ent Entity) error {
-	q := insertQuery(strings.Join(GetColumns(ent), ","), strings.Join(GetPlaceholders(ent), ","), ent.GetTable())
-	_, err := c.DB.Conn.Query(fmt.Sprintf(q), GetValues(ent)...)
-	if err != nil {
+	return c.runHooks(ent, c.saveHook(ent), func(r Repository) error {
+		return r.save(ent)
+	})
+}
+
+func (c Repository) save(ent Entity) error {
+	if changes, tracked := c.Changes(ent); tracked {
+		return c.saveChanges(ent, changes)
+	}
+	c.keep(ent)
+	if _, err := c.touchTimestamps(ent, true); err != nil {
 		return err
 	}
-	ents, err := ent.GetChildren()
-	if err != nil {
+	if err := generateIDs(ent); err != nil {
 		return err
 	}
-	err = c.SaveAll(ents)
-	if err != nil {
+	fields, auto := insertFields(ent)
+	q := insertQuery(strings.Join(columnNames(fields), ","), strings.Join(placeholderList(len(fields)), ","), ent.GetTable())
+	if err := c.insertRow(ent, "SAVE", q+c.upsertClause(ent, fields), fields, auto, c.upsert.Returning); err != nil {
 		return err
 	}
-	return err
+	c.remember(ent)
+	return c.saveRelated([]Entity{ent})
 }
 
 func (c Repository) SaveAll(ents []Entity) error {
-	save := make([]Entity, 0)
-	joins := make([]IJoinTable, 0)
+	ops := make([]hookOp, 0)
 	for _, v := range ents {
-		placeholders := make([]string, 0)
-		values := make([]interface{}, 0)
-		save = append(save, v)
-		placeholders = append(placeholders, strings.Join(GetPlaceholders(v), ","))
-		values = append(values, GetValues(v))
-		children, err := v.GetChildren()
-		if err != nil {
+		op := c.saveHook(v)
+		if err := c.callHook(v, "Before"+string(op), beforeHook(v, op)); err != nil {
 			return err
 		}
-		err = c.Save(v)
-		if err != nil {
-			return err
+		ops = append(ops, op)
+	}
+	if c.tx == nil {
+		for i, v := range ents {
+			if afterHook(v, ops[i]) != nil {
+				return c.WithTx(c.context(), func(tx Repository) error {
+					return tx.saveAll(ents, ops)
+				})
+			}
 		}
-		save, joins, err = c.SaveChildren(v, children, save)
 	}
-	for _, j := range joins {
-		if e, ok := j.(Entity); ok {
-			err := c.Save(e)
-			if err != nil {
-				return err
+	return c.saveAll(ents, ops)
+}
+
+func (c Repository) saveAll(ents []Entity, ops []hookOp) error {
+	tables := make([]string, 0)
+	groups := make(map[string][]Entity)
+	saved := make([]Entity, 0)
+	batchErr := &BatchError{}
+	for i, v := range ents {
+		if changes, tracked := c.Changes(v); tracked {
+			if err := c.saveChanges(v, changes); err != nil {
+				batchErr.add(v.GetTable(), i, 1, err)
 			}
+			continue
+		}
+		key := v.GetTable() + ":" + reflect.TypeOf(v).String()
+		if _, ok := groups[key]; !ok {
+			tables = append(tables, key)
+		}
+		groups[key] = append(groups[key], v)
+		saved = append(saved, v)
+	}
+	for _, key := range tables {
+		failed := len(batchErr.Chunks)
+		c.saveBatch(groups[key], batchErr)
+		if len(batchErr.Chunks) == failed {
+			for _, e := range groups[key] {
+				c.remember(e)
+			}
+		}
+	}
+	if err := c.saveRelated(saved); err != nil {
+		batchErr.add("", 0, 0, err)
+	}
+	if err := batchErr.orNil(); err != nil {
+		return err
+	}
+	for i, v := range ents {
+		if err := c.callHook(v, "After"+string(ops[i]), afterHook(v, ops[i])); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -230,52 +372,140 @@ func (c Repository) SaveChildren(parent Entity, children []Entity, save []Entity
 }
 
 func (c Repository) Update(e Entity, id string, updates []KVP) error {
-	query := "UPDATE TABLE " + e.GetTable()
+	return c.runHooks(e, hookUpdate, func(r Repository) error {
+		return r.update(e, id, updates)
+	})
+}
+
+func (c Repository) update(e Entity, id string, updates []KVP) error {
+	c.keep(e)
+	touched, err := c.touchTimestamps(e, false)
+	if err != nil {
+		return err
+	}
+	updates = mergeUpdates(updates, touched)
+	version := versionField(e)
+	var current int64
+	if version != nil {
+		if current, err = versionValue(version); err != nil {
+			return err
+		}
+		updates = withoutColumn(updates, version.Field.Tag.Get("column"))
+	}
+	sensitive := sensitiveColumns(e)
+	sets := make([]string, 0)
 	values := make([]interface{}, 0)
 	for _, kvp := range updates {
-		query += " SET " + kvp.Key + " = ?"
+		sets = append(sets, kvp.Key+" = ?")
+		if sensitive[strings.ToLower(kvp.Key)] {
+			values = append(values, Redact(kvp.Value))
+			continue
+		}
 		values = append(values, kvp.Value)
 	}
-	query += " WHERE ID = ?"
-	values = append(values, id)
-	rows, err := c.DB.Conn.Query(query, values...)
-	defer rows.Close()
-	return handleSQLError(rows, e, "UPDATE", err, id)
+	where := " WHERE ID = ?"
+	values = append(values, redactColumn(e, primaryKeyColumns(e)[0], id))
+	if version != nil {
+		column := version.Field.Tag.Get("column")
+		sets = append(sets, column+" = "+column+" + 1")
+		where += " AND " + column + " = ?"
+		values = append(values, redactColumn(e, column, current))
+	}
+	query := "UPDATE " + e.GetTable() + " SET " + strings.Join(sets, ", ") + where
+	res, err := c.exec(stmt(e, OpUpdate, query, values...))
+	c.invalidate(e.GetTable())
+	if err = handleSQLError(nil, e, "UPDATE", err, id); err != nil {
+		return err
+	}
+	written := make([]string, 0)
+	for _, kvp := range updates {
+		written = append(written, kvp.Key)
+	}
+	if version == nil {
+		if c.tracker != nil {
+			c.tracker.refresh(e, written)
+		}
+		return nil
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return handleSQLError(nil, e, "UPDATE", err, id)
+	}
+	if affected == 0 {
+		found, err := c.exists(e, id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return &Error{Op: "UPDATE", Table: e.GetTable(), ID: id, Kind: ErrNotFound, Err: sql.ErrNoRows}
+		}
+		return &Error{Op: "UPDATE", Table: e.GetTable(), ID: id, Kind: ErrStaleEntity, Err: fmt.Errorf("version %d: %w", current, ErrStaleEntity)}
+	}
+	setVersion(version, current+1)
+	if c.tracker != nil {
+		c.tracker.refresh(e, append(written, version.Field.Tag.Get("column")))
+	}
+	return nil
 }
 
 func (c Repository) Insert(e Entity) error {
-	rows, err := c.DB.Conn.Query("INSERT INTO "+e.GetTable()+" ("+strings.Join(GetColumns(e), ",")+") VALUES ("+strings.Join(GetPlaceholders(e), ", ")+")", GetValues(e)...)
-	defer rows.Close()
-	return handleSQLError(rows, e, "INSERT", err, "")
+	return c.runHooks(e, hookInsert, func(r Repository) error {
+		return r.insert(e)
+	})
+}
+
+func (c Repository) insert(e Entity) error {
+	c.keep(e)
+	if _, err := c.touchTimestamps(e, true); err != nil {
+		return err
+	}
+	if err := generateIDs(e); err != nil {
+		return err
+	}
+	fields, auto := insertFields(e)
+	q := insertQuery(strings.Join(columnNames(fields), ","), strings.Join(placeholderList(len(fields)), ", "), e.GetTable())
+	if err := c.insertRow(e, "INSERT", q, fields, auto, false); err != nil {
+		return err
+	}
+	c.remember(e)
+	return nil
 }
 
 func (c Repository) Delete(e Entity) error {
+	return c.runHooks(e, hookDelete, func(r Repository) error {
+		return r.remove(e)
+	})
+}
+
+func (c Repository) remove(e Entity) error {
+	c.keep(e)
 	id, err := e.GetID()
 	if err != nil {
 		return err
 	}
-	rows, err := c.DB.Conn.Query("DELETE FROM "+e.GetTable()+" WHERE ID = ?", id)
-	defer rows.Close()
-	return handleSQLError(rows, e, "DELETE", err, "")
+	if f := softDeleteField(e); f != nil {
+		if c.cascades(e) {
+			return c.cascadeDelete(e, true)
+		}
+		return c.softDelete(e, f, id)
+	}
+	if err := c.hardDelete(e, id); err != nil {
+		return err
+	}
+	if c.tracker != nil {
+		c.tracker.Forget(e)
+	}
+	if c.identity != nil {
+		c.identity.Remove(e)
+	}
+	return nil
 }
 
 func handleSQLError(rows *sql.Rows, e Entity, action string, err error, id string) error {
-	if id == "" {
-		if err != nil {
-			return fmt.Errorf(e.GetTable()+" %s: %v", action, err)
-		}
-		if err = rows.Err(); err != nil {
-			return fmt.Errorf(e.GetTable()+" %s: %v", action, err)
-		}
-	} else {
-		if err != nil {
-			return fmt.Errorf(e.GetTable()+" %s %q: %v", action, id, err)
-		}
-		if err = rows.Err(); err != nil {
-			return fmt.Errorf(e.GetTable()+" %s %q: %v", action, id, err)
-		}
+	if err == nil && rows != nil {
+		err = rows.Err()
 	}
-	return nil
+	return sqlError(e.GetTable(), action, id, err)
 }
 
 func GetFieldDataTypes(f []FieldDataTypes) string {
@@ -325,48 +555,80 @@ func (c Repository) NewFieldDataTypes() []FieldDataTypes {
 }
 
 func (c *Repository) RegisterTable(ent ...Entity) {
+	if c.relations == nil {
+		c.relations = NewRelationRegistry()
+	}
 	for _, e := range ent {
 		c.Tables = append(c.Tables, e)
+		if _, err := c.relations.Register(e); err != nil {
+			c.logger().Error("register table", slog.String("table", e.GetTable()), slog.Any("error", err))
+		}
 	}
 }
 
 func (c Repository) GetChildIds(parent Entity, child Entity) ([]string, error) {
-	parentName := CamelToSnake(reflect.TypeOf(parent).Name())
-	childName := CamelToSnake(reflect.TypeOf(child).Name())
+	parentName := CamelToSnake(entityType(parent).Name())
+	childName := CamelToSnake(entityType(child).Name())
 	parentId, err := parent.GetID()
 	if err != nil {
 		return nil, err
 	}
-	rows, err := c.DB.Conn.Query(fmt.Sprintf("SELECT %s_id FROM %s_%s WHERE %s_id = ?", childName, parentName, childName, parentName), parentId)
+	q := fmt.Sprintf("SELECT %s_id FROM %s_%s WHERE %s_id = ?", childName, parentName, childName, parentName)
+	if rel, ok := c.relationBetween(parent, child); ok {
+		switch rel.Kind {
+		case ManyToMany:
+			q = fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", rel.JoinReferences, rel.JoinTable, rel.JoinForeignKey)
+		case HasOne, HasMany:
+			q = fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", primaryKeyColumns(child)[0], rel.Target, rel.ForeignKey)
+		}
+	}
+	rows, err := c.query(stmt(parent, OpSelect, q, parentId))
+	if err != nil {
+		return nil, fmt.Errorf("%s GetChildIds: %w", parentName, err)
+	}
+	defer rows.Close()
 	results := make([]string, 0)
 	for rows.Next() {
 		var result string
 		if err := rows.Scan(&result); err != nil {
-			return nil, fmt.Errorf("%s GetChildIds:%v", parentName, err)
+			return nil, fmt.Errorf("%s GetChildIds: %w", parentName, err)
 		}
 		results = append(results, result)
 	}
-	return results, nil
+	return results, rows.Err()
 }
 
 func (c Repository) GetChildren(parentType Entity, childType Entity) ([]Entity, error) {
 	childIds, err := c.GetChildIds(parentType, childType)
 	if err != nil {
-		return nil, fmt.Errorf("get child ids: %v", err)
+		return nil, fmt.Errorf("get child ids: %w", err)
 	}
 	children, err := c.All(childIds, childType)
 	if err != nil {
-		return nil, fmt.Errorf("get child objects: %v", err)
+		return nil, fmt.Errorf("get child objects: %w", err)
 	}
 	return children, nil
 }
 
 func (c *Alters) GenerateSQL(tableName string) {
 	tableName = CamelToSnake(tableName)
-	c.SQL = fmt.Sprintf("ALTER TABLE `%s` ADD CONSTRAINT `%s` FOREIGN KEY (`%s`) REFERENCES `%s` (`id`)", tableName, fmt.Sprintf("fk_%s_%s", tableName, c.Reference), c.ForeignKey, c.Reference)
+	c.SQL = fmt.Sprintf("ALTER TABLE `%s` ADD CONSTRAINT `%s` FOREIGN KEY (`%s`) REFERENCES `%s` (`id`)", tableName, fmt.Sprintf("fk_%s_%s", tableName, c.ForeignKey), c.ForeignKey, c.Reference)
 }
 
 func (c Repository) CreateTables() error {
+	registry := c.registry()
+	invalid := make([]error, 0)
+	for _, e := range c.Tables {
+		if err := checkGenerate(e); err != nil {
+			return err
+		}
+		if _, err := registry.Register(e); err != nil {
+			invalid = append(invalid, fmt.Errorf("%s: %w", e.GetTable(), err))
+		}
+	}
+	if len(invalid) > 0 {
+		return errors.Join(invalid...)
+	}
 	out := make(chan map[string][]Column, len(c.Tables))
 	var wg sync.WaitGroup
 	cols := readAnnotations(c, &wg, out)
@@ -374,11 +636,12 @@ func (c Repository) CreateTables() error {
 	joins := make([]JoinTable, 0)
 	alters := make([]Alters, 0)
 	tables := make([]string, 0)
-	tableName := ""
+	inline := make([]Column, 0)
 	for _, columns := range cols {
 		for key, attributes := range columns {
 			columns := make([]string, 0)
 			for _, attribute := range attributes {
+				tableName := CamelToSnake(key)
 				if attribute.TableName != "" {
 					tableName = attribute.TableName
 				}
@@ -388,44 +651,16 @@ func (c Repository) CreateTables() error {
 					column = attribute.SQLDefinition
 				}
 				if attribute.ColumnString != "" && attribute.JoinString != "" {
-					first := strings.Split(attribute.JoinString, ",")
-					t1 := strings.Split(first[0], ":")
-					t2 := strings.Split(first[1], ":")
-					jt := JoinTable{
-						FirstTable:  t1[0],
-						FirstKey:    t1[1],
-						SecondTable: t2[0],
-						SecondKey:   t2[1],
-					}
-					if tableName == "" {
-						tableName = CamelToSnake(fmt.Sprintf("%s_%s", jt.FirstTable, jt.SecondTable))
-					}
-					jt.SQL = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s varchar(35) not null, %s varchar(35) not null)", tableName, jt.FirstKey, jt.SecondKey)
-					alter1 := Alters{
-						Table:      tableName,
-						Reference:  jt.FirstTable,
-						ForeignKey: jt.FirstKey,
-					}
-					alter1.GenerateSQL(tableName)
-					alter2 := Alters{
-						Table:      tableName,
-						Reference:  jt.SecondTable,
-						ForeignKey: jt.SecondKey,
-					}
-					alter2.GenerateSQL(tableName)
-					alters = append(alters, alter1, alter2)
-					joins = append(joins, jt)
+					inline = append(inline, attribute)
 				}
 				if attribute.ReferenceString != "" {
-					if tableName == "" {
-						tableName = CamelToSnake(key)
-					}
 					at := Alters{
 						Table:     tableName,
 						Reference: attribute.ReferenceString,
 						Key:       attribute.ColumnString,
 					}
-					if attribute.ForeignKey != "" {
+					at.ForeignKey = attribute.ColumnString
+					if attribute.ForeignKey != "" && attribute.ForeignKey != "true" {
 						at.ForeignKey = attribute.ForeignKey
 					}
 					at.GenerateSQL(at.Table)
@@ -440,14 +675,30 @@ func (c Repository) CreateTables() error {
 			}
 		}
 	}
-	erchan := make(chan string)
+	relJoins, relAlters := c.relationDDL()
+	covered := c.joinTableNames()
+	for _, attribute := range inline {
+		table, jt, at, err := c.inlineJoin(attribute)
+		if err != nil {
+			return err
+		}
+		if covered[table] {
+			continue
+		}
+		covered[table] = true
+		joins = append(joins, jt)
+		alters = append(alters, at...)
+	}
+	joins = append(joins, relJoins...)
+	alters = uniqueAlters(append(alters, relAlters...))
+	erchan := make(chan error, len(tables)+len(joins)+len(alters))
 	for _, k := range tables {
 		wg.Add(1)
 		go func(s string) {
 			defer wg.Done()
-			_, err := c.DB.Conn.Query(s)
+			_, err := c.exec(tableStmt("", OpDDL, s))
 			if err != nil {
-				erchan <- fmt.Sprintf("%s:%s", s, err.Error())
+				erchan <- fmt.Errorf("%s: %w", s, err)
 			}
 		}(k)
 	}
@@ -455,9 +706,9 @@ func (c Repository) CreateTables() error {
 		wg.Add(1)
 		go func(s string) {
 			defer wg.Done()
-			_, err := c.DB.Conn.Query(s)
+			_, err := c.exec(tableStmt("", OpDDL, s))
 			if err != nil {
-				erchan <- fmt.Sprintf("%s:%s", s, err.Error())
+				erchan <- fmt.Errorf("%s: %w", s, err)
 			}
 		}(k.SQL)
 	}
@@ -466,28 +717,43 @@ func (c Repository) CreateTables() error {
 		wg.Add(1)
 		go func(s string) {
 			defer wg.Done()
-			_, err := c.DB.Conn.Query(s)
-			if err != nil {
-				erchan <- fmt.Sprintf("%s:%s", s, err.Error())
+			_, err := c.exec(tableStmt("", OpDDL, s))
+			if err != nil && !constraintExists(err) {
+				erchan <- fmt.Errorf("%s: %w", s, err)
 			}
 		}(k.SQL)
 	}
-	go func() {
-		wg.Wait()
-		defer close(erchan)
-	}()
-	log.Printf("error count: %d", len(erchan))
-	open := true
-	i := 0
-	for open {
-		er, open := <-erchan
-		log.Println(er)
-		i++
-		if !open {
-			break
+	wg.Wait()
+	close(erchan)
+	errs := make([]error, 0)
+	for er := range erchan {
+		errs = append(errs, er)
+	}
+	return errors.Join(errs...)
+}
+
+func constraintExists(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1826 || myErr.Number == 1061
+	}
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		return pgErr.SQLState() == "42710"
+	}
+	return false
+}
+
+func uniqueAlters(alters []Alters) []Alters {
+	results := make([]Alters, 0)
+	seen := make(map[string]bool)
+	for _, a := range alters {
+		if !seen[a.SQL] {
+			seen[a.SQL] = true
+			results = append(results, a)
 		}
 	}
-	return nil
+	return results
 }
 
 func CamelToSnake(s string) string {
@@ -519,10 +785,10 @@ func (c *Column) GenerateSQL() {
 				ds = fmt.Sprintf(" DEFAULT %s", c.DefaultString)
 			}
 			switch c.TypeString {
-			case "varchar(35)":
+			case "varchar(36)":
 				fallthrough
 			case "uuid.UUID":
-				c.SQLDefinition += " varchar(35)"
+				c.SQLDefinition += " varchar(36)"
 				if c.NullString != "" {
 					c.SQLDefinition += " NOT NULL"
 				}
@@ -550,14 +816,25 @@ func (c *Column) GenerateSQL() {
 					c.SQLDefinition += " NOT NULL"
 				}
 				c.SQLDefinition += ds
-			case "int":
-				fallthrough
-			case "time.Duration":
-				c.SQLDefinition += " INT"
+			case "char(26)":
+				c.SQLDefinition += " CHAR(26)"
 				if c.NullString != "" {
 					c.SQLDefinition += " NOT NULL"
 				}
 				c.SQLDefinition += ds
+				if c.PrimaryKey != "" {
+					c.SQLDefinition += " PRIMARY KEY"
+				}
+			case "bigint":
+				c.SQLDefinition += " BIGINT"
+				c.generateIntegerSQL(ds)
+			case "integer":
+				fallthrough
+			case "int":
+				fallthrough
+			case "time.Duration":
+				c.SQLDefinition += " INT"
+				c.generateIntegerSQL(ds)
 			case "long":
 				c.SQLDefinition += " TEXT"
 				if c.NullString != "" {
@@ -577,6 +854,20 @@ func (c *Column) GenerateSQL() {
 	}
 }
 
+func (c *Column) generateIntegerSQL(ds string) {
+	if c.Generate == GenerateAutoIncrement {
+		c.SQLDefinition += " NOT NULL AUTO_INCREMENT"
+	} else {
+		if c.NullString != "" {
+			c.SQLDefinition += " NOT NULL"
+		}
+		c.SQLDefinition += ds
+	}
+	if c.PrimaryKey != "" {
+		c.SQLDefinition += " PRIMARY KEY"
+	}
+}
+
 func readAnnotations(c Repository, wg *sync.WaitGroup, out chan map[string][]Column) []map[string][]Column {
 	cols := make([]map[string][]Column, 0)
 	if len(c.Tables) > 0 {
@@ -586,12 +877,10 @@ func readAnnotations(c Repository, wg *sync.WaitGroup, out chan map[string][]Col
 				defer wg.Done()
 				columns := make(map[string][]Column)
 				t := reflect.TypeOf(e)
-				var name string
 				if t.Kind() == reflect.Ptr {
-					name = t.Elem().Name()
-				} else {
-					name = t.Name()
+					t = t.Elem()
 				}
+				name := e.GetTable()
 				processTypeForColumns(t, columns, name)
 				out <- columns
 			}(c.Tables[i], i, out)
@@ -613,38 +902,63 @@ func readAnnotations(c Repository, wg *sync.WaitGroup, out chan map[string][]Col
 }
 
 func GetColumns(ent Entity) []string {
+	return columnNames(columnFields(ent))
+}
+
+func GetPlaceholders(ent Entity) []string {
+	return placeholderList(len(columnFields(ent)))
+}
+
+func GetValues(ent Entity) []interface{} {
+	return columnValues(columnFields(ent))
+}
+
+func columnNames(fields []fieldRef) []string {
 	results := make([]string, 0)
-	r := reflect.TypeOf(ent)
-	for i := 0; i < r.NumField(); i++ {
-		field := r.Field(i)
-		if f, ok := field.Tag.Lookup("column"); ok {
-			results = append(results, f)
-		}
+	for _, f := range fields {
+		results = append(results, f.Field.Tag.Get("column"))
 	}
 	return results
 }
 
-func GetPlaceholders(ent Entity) []string {
-	results := make([]string, 0)
-	r := reflect.TypeOf(ent)
-	for i := 0; i < r.NumField(); i++ {
-		field := r.Field(i)
-		if _, ok := field.Tag.Lookup("column"); ok {
-			results = append(results, "?")
+func columnValues(fields []fieldRef) []interface{} {
+	results := make([]interface{}, 0)
+	for _, f := range fields {
+		if tagEnabled(f.Field, "sensitive") {
+			results = append(results, Redact(fieldValue(f)))
+			continue
 		}
+		results = append(results, fieldValue(f))
 	}
 	return results
 }
 
-func GetValues(ent Entity) []interface{} {
-	results := make([]interface{}, 0)
-	v := reflect.ValueOf(ent).Elem()
-	t := reflect.TypeOf(ent)
-	for i := 0; i < v.NumField(); i++ {
-		if _, ok := t.Field(i).Tag.Lookup("column"); ok {
-			results = append(results, v.Field(i).Interface())
+func fieldValue(f fieldRef) interface{} {
+	if f.Value.Kind() == reflect.Ptr {
+		if target, ok := relationTargetType(f.Value.Type()); ok {
+			if f.Value.IsNil() {
+				return nil
+			}
+			te, _ := entityForType(target)
+			return relationKey(f.Value.Interface().(Entity), primaryKeyColumns(te)[0])
 		}
 	}
+	return f.Value.Interface()
+}
+
+func stringArgs(values []string) []interface{} {
+	results := make([]interface{}, 0)
+	for _, v := range values {
+		results = append(results, v)
+	}
+	return results
+}
+
+func placeholderList(n int) []string {
+	results := make([]string, 0)
+	for i := 0; i < n; i++ {
+		results = append(results, "?")
+	}
 	return results
 }
 
@@ -655,17 +969,9 @@ func GetField(v Entity, fd string) interface{} {
 }
 
 func insertQuery(columns, placeholders, table string) string {
-	return fmt.Sprintf("INSERT (%s) VALUES (%s) INTO %s ON DUPLICATE KEY UPDATE", columns, placeholders, table)
-}
-
-func selectQuery(table string) string {
-	return fmt.Sprintf("SELECT * FROM %s t WHERE t.id = ?`", table)
-}
-
-func selectInQuery(table, placeholders string) string {
-	return fmt.Sprintf("SELECT * FROM %s t WHERE t.id IN (%s)", table, placeholders)
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, columns, placeholders)
 }
 
 func deleteQuery(table string) string {
-	return fmt.Sprintf("DELETE FROM %s t WHERE t.id = ?`", table)
+	return fmt.Sprintf("DELETE FROM %s t WHERE t.id = ?", table)
 }
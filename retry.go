@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
+	Retryable   func(err error) bool
+	OnRetry     func(ctx context.Context, attempt int, err error, delay time.Duration)
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   50 * time.Millisecond,
+	MaxDelay:    time.Second,
+	Jitter:      0.5,
+}
+
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || connectionLost(err) {
+		return true
+	}
+	if errors.Is(err, ErrDeadlock) || errors.Is(err, ErrLockTimeout) || errors.Is(err, ErrSerializationFailure) {
+		return true
+	}
+	kind := classify(err)
+	return kind == ErrDeadlock || kind == ErrLockTimeout || kind == ErrSerializationFailure
+}
+
+func connectionLost(err error) bool {
+	if errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && strings.HasPrefix(pgErr.SQLState(), "08")
+}
+
+type commitError struct {
+	err error
+}
+
+func (e *commitError) Error() string {
+	return "commit: " + e.err.Error()
+}
+
+func (e *commitError) Unwrap() error {
+	return e.err
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsTransient(err)
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay < p.BaseDelay) {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 && delay > 0 {
+		delay -= time.Duration(rand.Float64() * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+func (c Repository) WithRetry(p RetryPolicy) Repository {
+	c.retry = &p
+	return c
+}
+
+func (c Repository) retrying(replayable bool, fn func(attempt int) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(attempt)
+		if err == nil || c.retry == nil || attempt >= c.retry.MaxAttempts || !c.retry.retryable(err) {
+			return err
+		}
+		var commitErr *commitError
+		if connectionLost(err) && (!replayable || errors.As(err, &commitErr)) {
+			return err
+		}
+		delay := c.retry.backoff(attempt)
+		if c.retry.OnRetry != nil {
+			c.retry.OnRetry(c.context(), attempt, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.context().Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		for _, retried := range c.DB.retried {
+			retried(c.context(), err)
+		}
+	}
+}
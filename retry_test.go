@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var deadlock = &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+func fastRetry() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, BaseDelay: time.Nanosecond}
+}
+
+func TestBackoffDoublesUpToMaxDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 35 * time.Millisecond}
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 35 * time.Millisecond, 4: 35 * time.Millisecond, 70: 35 * time.Millisecond} {
+		if got := p.backoff(attempt); got != want {
+			t.Errorf("attempt %d: got %v, want %v", attempt, got, want)
+		}
+	}
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(2); got < 10*time.Millisecond || got > 20*time.Millisecond {
+			t.Fatalf("jittered delay %v outside [10ms, 20ms]", got)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	for err, want := range map[error]bool{
+		driver.ErrBadConn:                      true,
+		deadlock:                               true,
+		&mysql.MySQLError{Number: 1205}:        true,
+		&mysql.MySQLError{Number: 1062}:        false,
+		mysql.ErrInvalidConn:                   true,
+		io.ErrUnexpectedEOF:                    true,
+		fmt.Errorf("write: %w", syscall.EPIPE): true,
+		pgError("08006"):                       true,
+		pgError("40001"):                       true,
+		pgError("40P01"):                       true,
+		pgError("23505"):                       false,
+		errors.New("syntax error"):             false,
+	} {
+		if got := IsTransient(err); got != want {
+			t.Errorf("%v: got %v, want %v", err, got, want)
+		}
+	}
+}
+
+func TestRetryRunsStatementAgain(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("SELECT", widgetColumns, widgetRow("w1", "a", 0))
+	d.failOn("SELECT", deadlock, 1)
+	retries := make([]int, 0)
+	p := fastRetry()
+	p.OnRetry = func(ctx context.Context, attempt int, err error, delay time.Duration) {
+		retries = append(retries, attempt)
+	}
+	w := &widget{}
+	if err := (Repository{DB: db}).WithRetry(p).Take(w, "w1"); err != nil {
+		t.Fatal(err)
+	}
+	if w.Name != "a" || d.count("SELECT") != 2 || len(retries) != 1 || retries[0] != 1 {
+		t.Fatalf("name %q after %v with retries %v", w.Name, d.statements(), retries)
+	}
+}
+
+func TestRetryStopsAtMaxAttempts(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.failOn("SELECT", deadlock, 0)
+	err := (Repository{DB: db}).WithRetry(fastRetry()).Take(&widget{}, "w1")
+	if !errors.Is(err, ErrDeadlock) || d.count("SELECT") != 3 {
+		t.Fatalf("got %v after %v", err, d.statements())
+	}
+}
+
+func TestRetryStopsWhenContextIsCancelled(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.failOn("SELECT", deadlock, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}
+	p.OnRetry = func(context.Context, int, error, time.Duration) {
+		cancel()
+	}
+	done := make(chan error)
+	go func() {
+		done <- (Repository{DB: db}).WithContext(ctx).WithRetry(p).Take(&widget{}, "w1")
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrDeadlock) || d.count("SELECT") != 1 {
+			t.Fatalf("got %v after %v", err, d.statements())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("retry waited past the cancelled context")
+	}
+}
+
+func TestRetryRereadsButDoesNotRewriteAfterConnectionLoss(t *testing.T) {
+	d, db := newFakeDB(t)
+	r := Repository{DB: db}.WithRetry(fastRetry())
+	d.returns("SELECT", widgetColumns, widgetRow("w1", "a", 0))
+	d.failOn("SELECT", mysql.ErrInvalidConn, 1)
+	if err := r.Take(&widget{}, "w1"); err != nil || d.count("SELECT") != 2 {
+		t.Fatalf("read got %v after %v", err, d.statements())
+	}
+	d.failOn("INSERT", mysql.ErrInvalidConn, 1)
+	if err := r.Insert(&widget{Name: "b"}); !errors.Is(err, mysql.ErrInvalidConn) || d.count("INSERT") != 1 {
+		t.Fatalf("insert got %v after %v", err, d.statements())
+	}
+}
+
+func TestRetryDoesNotRepeatBulkLoads(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.failOn("LOAD DATA", deadlock, 1)
+	r := Repository{DB: db}.WithRetry(fastRetry())
+	ents := func(yield func(Entity) bool) {
+		yield(&widget{Name: "a"})
+	}
+	if _, err := r.BulkLoad(context.Background(), ents); !errors.Is(err, ErrDeadlock) || d.count("LOAD DATA") != 1 {
+		t.Fatalf("got %v after %v", err, d.statements())
+	}
+}
+
+func TestWithTxRestoresEntitiesBeforeRetry(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.failOn("COMMIT", deadlock, 1)
+	r := Repository{DB: db, Clock: fixedClock}.WithTracking().WithRetry(fastRetry())
+	w := &widget{Name: "a", Version: 1}
+	w.ID = "w1"
+	r.tracker.Track(w)
+	c := &counter{}
+	attempts := 0
+	err := r.WithTx(context.Background(), func(tx Repository) error {
+		attempts++
+		d.lastID = int64(40 + attempts)
+		if err := tx.Insert(c); err != nil {
+			return err
+		}
+		w.Name = "b"
+		return tx.Save(w)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 || c.Seq != 42 || w.Version != 2 {
+		t.Fatalf("attempts %d, seq %d, version %d", attempts, c.Seq, w.Version)
+	}
+	updates := make([]string, 0)
+	for _, s := range d.statements() {
+		if strings.HasPrefix(s, "INSERT INTO counter") && strings.Contains(s, "seq") {
+			t.Fatalf("retry inserted the first attempt's id: %s", s)
+		}
+		if strings.HasPrefix(s, "UPDATE widget") {
+			updates = append(updates, s)
+		}
+	}
+	if len(updates) != 2 || !strings.HasSuffix(updates[0], ",w1,1") || updates[1] != updates[0] {
+		t.Fatalf("updates %v", updates)
+	}
+	if changes, _ := r.Changes(w); len(changes) != 0 {
+		t.Fatalf("changes after commit: %v", changes)
+	}
+}
+
+func TestWithTxDoesNotRetryLostCommits(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.failOn("COMMIT", mysql.ErrInvalidConn, 1)
+	err := Repository{DB: db}.WithRetry(fastRetry()).WithTx(context.Background(), func(tx Repository) error {
+		return tx.Insert(&widget{Name: "a"})
+	})
+	if !errors.Is(err, mysql.ErrInvalidConn) || d.count("BEGIN") != 1 {
+		t.Fatalf("got %v after %v", err, d.statements())
+	}
+}
+
+func TestRetriesMetricCountsRetries(t *testing.T) {
+	d, db, _, reader := instrumented(t)
+	d.returns("SELECT", widgetColumns, widgetRow("w1", "a", 0))
+	d.failOn("SELECT", deadlock, 2)
+	r := Repository{DB: db}.WithRetry(fastRetry())
+	if err := r.Take(&widget{}, "w1"); err != nil {
+		t.Fatal(err)
+	}
+	d.failOn("COMMIT", deadlock, 1)
+	err := r.WithTx(context.Background(), func(tx Repository) error {
+		return tx.Take(&widget{}, "w1")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := sum(t, collect(t, reader)["db.client.retries"]); n != 3 {
+		t.Fatalf("db.client.retries = %d", n)
+	}
+}
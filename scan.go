@@ -0,0 +1,247 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const joinSeparator string = "__"
+
+func (c Repository) Joins(names ...string) Repository {
+	c.joins = append(append(make([]string, 0), c.joins...), names...)
+	return c
+}
+
+func (c Repository) joinRelations(ent Entity) ([]Relation, error) {
+	rels := make([]Relation, 0)
+	for _, name := range c.joins {
+		rel, err := c.Relation(ent, name)
+		if err != nil {
+			return nil, err
+		}
+		if rel.Kind != BelongsTo && rel.Kind != HasOne {
+			return nil, fmt.Errorf("%s: cannot join %s relation %s", ent.GetTable(), rel.Kind, name)
+		}
+		rels = append(rels, rel)
+	}
+	return rels, nil
+}
+
+func (c Repository) selectFrom(ent Entity) (string, error) {
+	if len(c.joins) == 0 {
+		return "SELECT * FROM " + ent.GetTable() + " t", nil
+	}
+	rels, err := c.joinRelations(ent)
+	if err != nil {
+		return "", err
+	}
+	columns := make([]string, 0)
+	for _, col := range GetColumns(ent) {
+		columns = append(columns, "t."+col+" AS "+col)
+	}
+	joins := make([]string, 0)
+	for i, rel := range rels {
+		alias := fmt.Sprintf("j%d", i)
+		target, _ := entityForType(rel.TargetType)
+		for _, col := range GetColumns(target) {
+			columns = append(columns, alias+"."+col+" AS "+rel.Name+joinSeparator+col)
+		}
+		on := fmt.Sprintf("%s.%s = t.%s", alias, primaryKeyColumns(target)[0], rel.ForeignKey)
+		if rel.Kind == HasOne {
+			on = fmt.Sprintf("%s.%s = t.%s", alias, rel.ForeignKey, rel.References)
+		}
+		joins = append(joins, fmt.Sprintf(" LEFT JOIN %s %s ON %s", rel.Target, alias, on))
+	}
+	return "SELECT " + strings.Join(columns, ", ") + " FROM " + ent.GetTable() + " t" + strings.Join(joins, ""), nil
+}
+
+func (c Repository) scanJoined(rows *sql.Rows, ent Entity) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	rels, err := c.joinRelations(ent)
+	if err != nil {
+		return err
+	}
+	nested := make(map[string]map[string]interface{})
+	own := make(map[string]interface{})
+	for i, col := range columns {
+		name, rest, found := strings.Cut(col, joinSeparator)
+		if !found {
+			own[strings.ToLower(col)] = values[i]
+			continue
+		}
+		key := strings.ToLower(name)
+		if nested[key] == nil {
+			nested[key] = make(map[string]interface{})
+		}
+		nested[key][strings.ToLower(rest)] = values[i]
+	}
+	if err := assignColumns(ent, own); err != nil {
+		return err
+	}
+	for _, rel := range rels {
+		row := nested[strings.ToLower(rel.Name)]
+		empty := true
+		for _, v := range row {
+			if v != nil {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			if rel.Kind != BelongsTo {
+				rel.assign(ent, nil)
+			}
+			continue
+		}
+		target := newEntity(reflect.New(rel.TargetType).Interface().(Entity))
+		if err := assignColumns(target, row); err != nil {
+			return fmt.Errorf("%s: %w", rel.Name, err)
+		}
+		rel.assign(ent, []Entity{c.remember(target)})
+	}
+	return nil
+}
+
+func assignColumns(ent Entity, row map[string]interface{}) error {
+	for _, f := range columnFields(ent) {
+		v, ok := row[strings.ToLower(f.Field.Tag.Get("column"))]
+		if !ok {
+			continue
+		}
+		if !f.Value.CanSet() {
+			return fmt.Errorf("cannot set %s, pass a pointer", f.Field.Name)
+		}
+		if target, isRel := relationTargetType(f.Value.Type()); isRel && f.Value.Kind() == reflect.Ptr {
+			if err := assignKeyStub(f.Value, target, v); err != nil {
+				return fmt.Errorf("%s: %w", f.Field.Name, err)
+			}
+			continue
+		}
+		if err := assignValue(f.Value, v); err != nil {
+			return fmt.Errorf("%s: %w", f.Field.Name, err)
+		}
+	}
+	return nil
+}
+
+func assignKeyStub(dst reflect.Value, target reflect.Type, key interface{}) error {
+	if key == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	stub, _ := entityForType(target)
+	column := primaryKeyColumns(stub)[0]
+	if err := assignColumns(stub, map[string]interface{}{strings.ToLower(column): key}); err != nil {
+		return err
+	}
+	if !dst.IsNil() && keyString(relationKey(dst.Interface().(Entity), column)) == keyString(relationKey(stub, column)) {
+		return nil
+	}
+	dst.Set(reflect.ValueOf(stub))
+	return nil
+}
+
+func assignValue(dst reflect.Value, src interface{}) error {
+	if dst.CanAddr() {
+		if scanner, ok := dst.Addr().Interface().(sql.Scanner); ok {
+			return scanner.Scan(src)
+		}
+	}
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		v := reflect.New(dst.Type().Elem())
+		if err := assignValue(v.Elem(), src); err != nil {
+			return err
+		}
+		dst.Set(v)
+		return nil
+	}
+	if b, ok := src.([]byte); ok && dst.Kind() != reflect.Slice {
+		src = string(b)
+	}
+	if dst.Type() == timeType {
+		switch t := src.(type) {
+		case time.Time:
+			dst.Set(reflect.ValueOf(t))
+			return nil
+		case string:
+			parsed, err := parseTime(t)
+			if err != nil {
+				return err
+			}
+			dst.Set(reflect.ValueOf(parsed))
+			return nil
+		}
+	}
+	switch dst.Kind() {
+	case reflect.String:
+		if t, ok := src.(time.Time); ok {
+			dst.SetString(t.Format(TimestampFormat))
+			return nil
+		}
+		dst.SetString(fmt.Sprint(src))
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(fmt.Sprint(src), 10, 64)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(fmt.Sprint(src), 10, 64)
+		if err != nil {
+			return err
+		}
+		dst.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(fmt.Sprint(src), 64)
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(n)
+		return nil
+	case reflect.Bool:
+		switch fmt.Sprint(src) {
+		case "1", "true", "TRUE", "t":
+			dst.SetBool(true)
+		default:
+			dst.SetBool(false)
+		}
+		return nil
+	}
+	v := reflect.ValueOf(src)
+	if v.Type().ConvertibleTo(dst.Type()) {
+		dst.Set(v.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot assign %T to %s", src, dst.Type())
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{TimestampFormat, "2006-01-02 15:04:05.999999", time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse time %q", s)
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+type IdentityMap struct {
+	mu      sync.Mutex
+	entries map[string]Entity
+	order   []string
+}
+
+func NewIdentityMap() *IdentityMap {
+	return &IdentityMap{entries: make(map[string]Entity)}
+}
+
+func identityKey(table string, id string) string {
+	return table + ":" + id
+}
+
+func (m *IdentityMap) Get(table string, id string) (Entity, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ent, ok := m.entries[identityKey(table, id)]
+	return ent, ok
+}
+
+func (m *IdentityMap) put(ent Entity) Entity {
+	id, err := ent.GetID()
+	if err != nil || id == "" {
+		return ent
+	}
+	key := identityKey(ent.GetTable(), id)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.entries[key]; ok {
+		return existing
+	}
+	m.entries[key] = ent
+	m.order = append(m.order, key)
+	return ent
+}
+
+func (m *IdentityMap) Remove(ent Entity) {
+	id, err := ent.GetID()
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, identityKey(ent.GetTable(), id))
+}
+
+func (m *IdentityMap) Entities() []Entity {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	results := make([]Entity, 0)
+	order := make([]string, 0)
+	for _, key := range m.order {
+		if ent, ok := m.entries[key]; ok {
+			results = append(results, ent)
+			order = append(order, key)
+		}
+	}
+	m.order = order
+	return results
+}
+
+type Session struct {
+	Repository
+	pending []Entity
+}
+
+func (c Repository) Session() *Session {
+	c.identity = NewIdentityMap()
+	c.tracker = NewTracker()
+	return &Session{Repository: c}
+}
+
+func (s *Session) Get(proto Entity, id string) (Entity, error) {
+	if ent, ok := s.identity.Get(proto.GetTable(), id); ok {
+		return ent, nil
+	}
+	result := newEntity(proto)
+	if reflect.TypeOf(result).Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("%s: session entities must be pointers", proto.GetTable())
+	}
+	if err := s.Take(result, id); err != nil {
+		return nil, err
+	}
+	if ent, ok := s.identity.Get(proto.GetTable(), id); ok {
+		return ent, nil
+	}
+	return result, nil
+}
+
+func SessionGet[T Entity](s *Session, id string) (T, error) {
+	var zero T
+	proto, err := prototype[T]()
+	if err != nil {
+		return zero, err
+	}
+	ent, err := s.Get(proto, id)
+	if err != nil {
+		return zero, err
+	}
+	result, ok := ent.(T)
+	if !ok {
+		return zero, fmt.Errorf("%s %q is loaded as %T, not %s", proto.GetTable(), id, ent, reflect.TypeFor[T]())
+	}
+	return result, nil
+}
+
+func loadedError(ent Entity, id string) error {
+	return fmt.Errorf("%s %q is already loaded in this session, read it with Session.Get", ent.GetTable(), id)
+}
+
+func (s *Session) Add(ents ...Entity) {
+	s.pending = append(s.pending, ents...)
+}
+
+func (s *Session) Commit(ctx context.Context) error {
+	type flush struct {
+		ent     Entity
+		changes []Change
+	}
+	dirty := make([]flush, 0)
+	for _, ent := range s.identity.Entities() {
+		if changes, tracked := s.tracker.Changes(ent); tracked && len(changes) > 0 {
+			dirty = append(dirty, flush{ent: ent, changes: changes})
+		}
+	}
+	pending := s.pending
+	if len(pending) == 0 && len(dirty) == 0 {
+		return nil
+	}
+	err := s.WithTx(ctx, func(tx Repository) error {
+		tx.tracker = nil
+		tx.identity = nil
+		for _, ent := range pending {
+			if err := tx.Save(ent); err != nil {
+				return err
+			}
+		}
+		for _, f := range dirty {
+			err := tx.runHooks(f.ent, hookUpdate, func(r Repository) error {
+				return r.saveChanges(f.ent, f.changes)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.pending = nil
+	for _, ent := range pending {
+		s.remember(ent)
+	}
+	for _, f := range dirty {
+		s.tracker.Track(f.ent)
+	}
+	return nil
+}
+
+func (s *Session) Clear() {
+	s.identity = NewIdentityMap()
+	s.tracker = NewTracker()
+	s.pending = nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSessionFlushesEditsMadeThroughTake(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("FROM widget", widgetColumns, widgetRow("w1", "a", 1))
+	s := Repository{DB: db, Clock: fixedClock}.Session()
+	w := &widget{}
+	if err := s.Take(w, "w1"); err != nil {
+		t.Fatal(err)
+	}
+	w.Name = "b"
+	if err := s.Commit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d.count("UPDATE widget SET name = ?") != 1 {
+		t.Fatalf("edit not flushed: %q", d.statements())
+	}
+}
+
+func TestSessionTakeRejectsSecondInstance(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.returns("FROM widget", widgetColumns, widgetRow("w1", "a", 1))
+	s := Repository{DB: db}.Session()
+	first, err := SessionGet[*widget](s, "w1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Take(first, "w1"); err != nil {
+		t.Fatalf("taking the mapped instance: %v", err)
+	}
+	if err := s.Take(&widget{}, "w1"); err == nil {
+		t.Fatal("expected an error taking a second instance of a mapped row")
+	}
+	again, err := SessionGet[*widget](s, "w1")
+	if err != nil || again != first {
+		t.Fatalf("expected the mapped instance, got %p and %p (%v)", again, first, err)
+	}
+	if n := d.count("SELECT"); n != 1 {
+		t.Fatalf("expected one query, got %d", n)
+	}
+}
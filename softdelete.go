@@ -0,0 +1,94 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type deletedScope int
+
+const (
+	excludeDeleted deletedScope = iota
+	includeDeleted
+	onlyDeleted
+)
+
+func (c Repository) WithDeleted() Repository {
+	c.deleted = includeDeleted
+	return c
+}
+
+func (c Repository) OnlyDeleted() Repository {
+	c.deleted = onlyDeleted
+	return c
+}
+
+func softDeleteField(ent Entity) *fieldRef {
+	for _, f := range columnFields(ent) {
+		if tagEnabled(f.Field, "softDelete") {
+			return &f
+		}
+	}
+	return nil
+}
+
+func (c Repository) scopeClause(ent Entity, alias string) string {
+	f := softDeleteField(ent)
+	if f == nil {
+		return ""
+	}
+	column := f.Field.Tag.Get("column")
+	if alias != "" {
+		column = alias + "." + column
+	}
+	switch c.deleted {
+	case includeDeleted:
+		return ""
+	case onlyDeleted:
+		return " AND " + column + " IS NOT NULL"
+	}
+	return " AND " + column + " IS NULL"
+}
+
+func (c Repository) Restore(e Entity) error {
+	f := softDeleteField(e)
+	if f == nil {
+		return fmt.Errorf("%s: restore requires a softDelete column", e.GetTable())
+	}
+	id, err := e.GetID()
+	if err != nil {
+		return err
+	}
+	c.keep(e)
+	_, err = c.exec(stmt(e, OpRestore, "UPDATE "+e.GetTable()+" SET "+f.Field.Tag.Get("column")+" = NULL WHERE ID = ?", id))
+	c.invalidate(e.GetTable())
+	if err = handleSQLError(nil, e, "RESTORE", err, id); err != nil {
+		return err
+	}
+	if f.Value.CanSet() {
+		f.Value.Set(reflect.Zero(f.Value.Type()))
+	}
+	return nil
+}
+
+func (c Repository) HardDelete(e Entity) error {
+	return c.runHooks(e, hookDelete, func(r Repository) error {
+		id, err := e.GetID()
+		if err != nil {
+			return err
+		}
+		return r.hardDelete(e, id)
+	})
+}
+
+func (c Repository) softDelete(e Entity, f *fieldRef, id string) error {
+	if !f.Value.CanSet() {
+		return fmt.Errorf("%s: cannot set %s, pass a pointer", e.GetTable(), f.Field.Name)
+	}
+	if err := setTime(f.Value, c.now()); err != nil {
+		return fmt.Errorf("%s %s: %w", e.GetTable(), f.Field.Name, err)
+	}
+	_, err := c.exec(stmt(e, OpDelete, "UPDATE "+e.GetTable()+" SET "+f.Field.Tag.Get("column")+" = ? WHERE ID = ?", f.Value.Interface(), id))
+	c.invalidate(e.GetTable())
+	return handleSQLError(nil, e, "DELETE", err, id)
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeleteSetsDeletedAt(t *testing.T) {
+	d, db := newFakeDB(t)
+	r := Repository{DB: db, Clock: fixedClock}
+	w := &widget{Name: "a"}
+	w.ID = "w1"
+	if err := r.Delete(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.DeletedAt == nil || *w.DeletedAt != "2024-01-02 03:04:05" {
+		t.Fatalf("deleted_at = %v", w.DeletedAt)
+	}
+	if got := d.statements()[0]; !strings.HasPrefix(got, "UPDATE widget SET deleted_at = ? WHERE ID = ?") {
+		t.Fatalf("delete ran %s", got)
+	}
+	if err := r.Restore(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.DeletedAt != nil {
+		t.Fatal("restore left deleted_at set")
+	}
+	if err := r.HardDelete(w); err != nil {
+		t.Fatal(err)
+	}
+	if d.count("DELETE FROM widget WHERE ID = ?") != 1 {
+		t.Fatalf("hard delete ran %v", d.statements())
+	}
+}
+
+func TestQueriesScopeDeletedRows(t *testing.T) {
+	d, db := newFakeDB(t)
+	r := Repository{DB: db}
+	for _, tc := range []struct {
+		repo Repository
+		want string
+	}{
+		{r, "AND t.deleted_at IS NULL"},
+		{r.OnlyDeleted(), "AND t.deleted_at IS NOT NULL"},
+		{r.WithDeleted(), "WHERE t.ID = ? -- w1"},
+	} {
+		d.reset()
+		if _, err := tc.repo.Select(&widget{}, "w1"); err != nil {
+			t.Fatal(err)
+		}
+		if got := d.statements()[0]; !strings.Contains(got, tc.want) {
+			t.Fatalf("%s does not contain %q", got, tc.want)
+		}
+	}
+}
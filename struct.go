@@ -1,20 +1,30 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"log/slog"
 
 	"github.com/go-sql-driver/mysql"
 )
 
 type DB struct {
-	Conn  *sql.DB
-	user  string
-	pass  string
-	net   string
-	addr  string
-	dbn   string
-	query string
+	Conn    *sql.DB
+	user    string
+	pass    string
+	net     string
+	addr    string
+	dbn     string
+	dialect string
+	query   string
+	args    []interface{}
+	err     error
+	cache   Cache
+	logger  *slog.Logger
+
+	interceptors []Interceptor
+	retried      []func(ctx context.Context, err error)
 }
 
 func (d *DB) SetUser(v string) {
@@ -57,6 +67,36 @@ func (d DB) GetDBN() string {
 	return d.dbn
 }
 
+func (d *DB) SetDialect(v string) {
+	d.dialect = v
+}
+
+func (d DB) GetDialect() string {
+	if d.dialect == "" {
+		return DialectMySQL
+	}
+	return d.dialect
+}
+
+func (d *DB) SetCache(v Cache) {
+	d.cache = v
+}
+
+func (d DB) GetCache() Cache {
+	return d.cache
+}
+
+func (d *DB) SetLogger(v *slog.Logger) {
+	d.logger = v
+}
+
+func (d DB) GetLogger() *slog.Logger {
+	if d.logger == nil {
+		return slog.Default()
+	}
+	return d.logger
+}
+
 func (d DB) GetCfg() *mysql.Config {
 	return &mysql.Config{
 		User:                 d.GetUser(),
@@ -81,7 +121,12 @@ func (d *DB) Connect() {
 }
 
 type Model struct {
-	ID      string `json:"id" column:"id" datatype:"uuid.UUID" null:"false" primaryKey:"true"`
-	Created string `json:"created" column:"created" datatype:"time.TIME" null:"false" default:"NOW()"`
-	Updated string `json:"updated" column:"updated" datatype:"time.TIME" null:"false" default:"NOW()"`
+	ID      string `json:"id" column:"id" datatype:"uuid.UUID" null:"false" primaryKey:"true" generate:"uuidv7"`
+	Created string `json:"created" column:"created" datatype:"time.TIME" null:"false" default:"NOW()" autoCreateTime:"true"`
+	Updated string `json:"updated" column:"updated" datatype:"time.TIME" null:"false" default:"NOW()" autoUpdateTime:"true"`
+}
+
+type SoftDeleteModel struct {
+	Model
+	DeletedAt *string `json:"deleted_at" column:"deleted_at" datatype:"time.TIME" null:"true" softDelete:"true"`
 }
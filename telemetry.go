@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName string = "github.com/mmarchio/go-db"
+
+type TelemetryOptions struct {
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+}
+
+type telemetry struct {
+	db       *DB
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+	rows     metric.Int64Counter
+	retries  metric.Int64Counter
+}
+
+func (d *DB) Instrument(opts TelemetryOptions) error {
+	if opts.TracerProvider == nil {
+		opts.TracerProvider = otel.GetTracerProvider()
+	}
+	if opts.MeterProvider == nil {
+		opts.MeterProvider = otel.GetMeterProvider()
+	}
+	meter := opts.MeterProvider.Meter(instrumentationName)
+	t := telemetry{db: d, tracer: opts.TracerProvider.Tracer(instrumentationName)}
+	var err error
+	if t.duration, err = meter.Float64Histogram("db.client.operation.duration", metric.WithUnit("s"), metric.WithDescription("Duration of database statements"), metric.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10)); err != nil {
+		return err
+	}
+	if t.errors, err = meter.Int64Counter("db.client.errors", metric.WithDescription("Number of failed database statements")); err != nil {
+		return err
+	}
+	if t.rows, err = meter.Int64Counter("db.client.rows", metric.WithDescription("Number of rows affected by database statements")); err != nil {
+		return err
+	}
+	if t.retries, err = meter.Int64Counter("db.client.retries", metric.WithDescription("Number of statements and transactions run again after a transient error")); err != nil {
+		return err
+	}
+	if err = d.instrumentPool(meter); err != nil {
+		return err
+	}
+	d.Use(t.intercept)
+	d.retried = append(d.retried, t.retried)
+	return nil
+}
+
+func (d *DB) instrumentPool(meter metric.Meter) error {
+	open, err := meter.Int64ObservableGauge("db.client.connections.open", metric.WithDescription("Open connections"))
+	if err != nil {
+		return err
+	}
+	inUse, err := meter.Int64ObservableGauge("db.client.connections.in_use", metric.WithDescription("Connections in use"))
+	if err != nil {
+		return err
+	}
+	idle, err := meter.Int64ObservableGauge("db.client.connections.idle", metric.WithDescription("Idle connections"))
+	if err != nil {
+		return err
+	}
+	maxOpen, err := meter.Int64ObservableGauge("db.client.connections.max", metric.WithDescription("Maximum open connections"))
+	if err != nil {
+		return err
+	}
+	waits, err := meter.Int64ObservableCounter("db.client.connections.waits", metric.WithDescription("Connections waited for"))
+	if err != nil {
+		return err
+	}
+	waitTime, err := meter.Float64ObservableCounter("db.client.connections.wait_time", metric.WithUnit("s"), metric.WithDescription("Time spent waiting for connections"))
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		if d.Conn == nil {
+			return nil
+		}
+		stats := d.Conn.Stats()
+		attrs := metric.WithAttributes(d.systemAttribute())
+		o.ObserveInt64(open, int64(stats.OpenConnections), attrs)
+		o.ObserveInt64(inUse, int64(stats.InUse), attrs)
+		o.ObserveInt64(idle, int64(stats.Idle), attrs)
+		o.ObserveInt64(maxOpen, int64(stats.MaxOpenConnections), attrs)
+		o.ObserveInt64(waits, stats.WaitCount, attrs)
+		o.ObserveFloat64(waitTime, stats.WaitDuration.Seconds(), attrs)
+		return nil
+	}, open, inUse, idle, maxOpen, waits, waitTime)
+	return err
+}
+
+func (d DB) systemAttribute() attribute.KeyValue {
+	switch d.GetDialect() {
+	case DialectPostgres:
+		return attribute.String("db.system", "postgresql")
+	case DialectSQLite:
+		return attribute.String("db.system", "sqlite")
+	}
+	return attribute.String("db.system", "mysql")
+}
+
+func (t telemetry) intercept(next Handler) Handler {
+	return func(ctx context.Context, st *Statement) error {
+		attrs := []attribute.KeyValue{
+			t.db.systemAttribute(),
+			attribute.String("db.operation", st.Operation),
+		}
+		if st.Table != "" {
+			attrs = append(attrs, attribute.String("db.sql.table", st.Table))
+		}
+		name := st.Operation
+		if st.Table != "" {
+			name += " " + st.Table
+		}
+		ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+		defer span.End()
+		err := next(ctx, st)
+		span.SetAttributes(attribute.String("db.statement", st.SQL))
+		if st.RowsAffected >= 0 {
+			span.SetAttributes(attribute.Int64("db.rows_affected", st.RowsAffected))
+			t.rows.Add(ctx, st.RowsAffected, metric.WithAttributes(attrs...))
+		}
+		if st.Attempt > 1 {
+			span.SetAttributes(attribute.Int("db.attempt", st.Attempt))
+		}
+		t.duration.Record(ctx, st.Duration.Seconds(), metric.WithAttributes(attrs...))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			t.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+		}
+		return err
+	}
+}
+
+func (t telemetry) retried(ctx context.Context, err error) {
+	t.retries.Add(ctx, 1, metric.WithAttributes(t.db.systemAttribute()))
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func instrumented(t *testing.T) (*fakeDriver, *DB, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	d, db := newFakeDB(t)
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	err := db.Instrument(TelemetryOptions{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, db, spans, reader
+}
+
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	results := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			results[m.Name] = m.Data
+		}
+	}
+	return results
+}
+
+func sum(t *testing.T, data metricdata.Aggregation) int64 {
+	t.Helper()
+	s, ok := data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("not an int64 sum: %T", data)
+	}
+	var n int64
+	for _, p := range s.DataPoints {
+		n += p.Value
+	}
+	return n
+}
+
+func TestTelemetrySpansAndMetrics(t *testing.T) {
+	_, db, spans, reader := instrumented(t)
+	w := &widget{Name: "a"}
+	w.ID = "w1"
+	if err := (Repository{DB: db}).Update(w, w.ID, []KVP{{Key: "name", Value: "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(ended))
+	}
+	span := ended[0]
+	if span.Name() != "UPDATE widget" {
+		t.Fatalf("span name %q", span.Name())
+	}
+	attrs := attribute.NewSet(span.Attributes()...)
+	if v, _ := attrs.Value("db.system"); v.AsString() != "mysql" {
+		t.Fatalf("db.system %q", v.AsString())
+	}
+	if v, _ := attrs.Value("db.rows_affected"); v.AsInt64() != 1 {
+		t.Fatalf("db.rows_affected %d", v.AsInt64())
+	}
+	metrics := collect(t, reader)
+	if n := sum(t, metrics["db.client.rows"]); n != 1 {
+		t.Fatalf("db.client.rows %d", n)
+	}
+	if h, ok := metrics["db.client.operation.duration"].(metricdata.Histogram[float64]); !ok || len(h.DataPoints) != 1 || h.DataPoints[0].Count != 1 {
+		t.Fatalf("duration histogram %#v", metrics["db.client.operation.duration"])
+	}
+	if _, ok := metrics["db.client.connections.open"]; !ok {
+		t.Fatal("missing pool gauges")
+	}
+}
+
+func TestTelemetryRecordsErrors(t *testing.T) {
+	d, db, spans, reader := instrumented(t)
+	d.failOn("DELETE", errors.New("boom"), 0)
+	if err := (Repository{DB: db}).HardDelete(&widget{}); err == nil {
+		t.Fatal("expected an error")
+	}
+	span := spans.Ended()[0]
+	if span.Status().Code != codes.Error || len(span.Events()) == 0 {
+		t.Fatalf("span status %v, %d events", span.Status(), len(span.Events()))
+	}
+	if n := sum(t, collect(t, reader)["db.client.errors"]); n != 1 {
+		t.Fatalf("db.client.errors %d", n)
+	}
+}
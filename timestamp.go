@@ -0,0 +1,75 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const TimestampFormat string = "2006-01-02 15:04:05"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (c Repository) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock()
+	}
+	return time.Now()
+}
+
+func (c Repository) touchTimestamps(ent Entity, create bool) ([]KVP, error) {
+	touched := make([]KVP, 0)
+	now := c.now()
+	for _, f := range columnFields(ent) {
+		autoCreate := tagEnabled(f.Field, "autoCreateTime")
+		autoUpdate := tagEnabled(f.Field, "autoUpdateTime")
+		if !autoUpdate && !(create && autoCreate && f.Value.IsZero()) {
+			continue
+		}
+		if !f.Value.CanSet() {
+			return nil, fmt.Errorf("%s: cannot set %s, pass a pointer", ent.GetTable(), f.Field.Name)
+		}
+		if err := setTime(f.Value, now); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", ent.GetTable(), f.Field.Name, err)
+		}
+		touched = append(touched, KVP{Key: f.Field.Tag.Get("column"), Value: f.Value.Interface()})
+	}
+	return touched, nil
+}
+
+func setTime(v reflect.Value, t time.Time) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == timeType:
+		v.Set(reflect.ValueOf(t))
+	case v.Kind() == reflect.String:
+		v.SetString(t.Format(TimestampFormat))
+	case v.Kind() == reflect.Int64 || v.Kind() == reflect.Int:
+		v.SetInt(t.Unix())
+	default:
+		return fmt.Errorf("unsupported timestamp type %s", v.Type())
+	}
+	return nil
+}
+
+func mergeUpdates(updates []KVP, extra []KVP) []KVP {
+	for _, e := range extra {
+		found := false
+		for _, u := range updates {
+			if strings.EqualFold(u.Key, e.Key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			updates = append(updates, e)
+		}
+	}
+	return updates
+}
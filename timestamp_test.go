@@ -0,0 +1,62 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInsertSetsTimestamps(t *testing.T) {
+	d, db := newFakeDB(t)
+	r := Repository{DB: db, Clock: fixedClock}
+	w := &widget{Name: "a"}
+	if err := r.Insert(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Created != "2024-01-02 03:04:05" || w.Updated != w.Created {
+		t.Fatalf("timestamps = %q, %q", w.Created, w.Updated)
+	}
+	if !strings.Contains(d.statements()[0], "2024-01-02 03:04:05") {
+		t.Fatalf("insert did not write timestamps: %s", d.statements()[0])
+	}
+}
+
+func TestUpdateRefreshesOnlyUpdated(t *testing.T) {
+	_, db := newFakeDB(t)
+	now := fixedClock()
+	r := Repository{DB: db, Clock: func() time.Time { return now }}
+	w := &widget{Name: "a"}
+	if err := r.Insert(w); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Hour)
+	if err := r.Update(w, w.ID, []KVP{{Key: "name", Value: "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if w.Created != "2024-01-02 03:04:05" || w.Updated != "2024-01-02 04:04:05" {
+		t.Fatalf("timestamps = %q, %q", w.Created, w.Updated)
+	}
+}
+
+func TestSetTimeTypes(t *testing.T) {
+	var s struct {
+		Text string
+		Time time.Time
+		Unix int64
+		Ptr  *time.Time
+		Bad  bool
+	}
+	now := fixedClock()
+	v := typeValue(&s)
+	for _, name := range []string{"Text", "Time", "Unix", "Ptr"} {
+		if err := setTime(v.FieldByName(name), now); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	if s.Text != "2024-01-02 03:04:05" || !s.Time.Equal(now) || s.Unix != now.Unix() || !s.Ptr.Equal(now) {
+		t.Fatalf("unexpected values %+v", s)
+	}
+	if err := setTime(v.FieldByName("Bad"), now); err == nil {
+		t.Fatal("expected an error for a bool field")
+	}
+}
@@ -0,0 +1,247 @@
+package db
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+	"weak"
+)
+
+const minSweep int = 64
+
+type Change struct {
+	Column string
+	Old    interface{}
+	New    interface{}
+}
+
+type Tracker struct {
+	mu        sync.Mutex
+	snapshots map[trackerKey]map[string]interface{}
+	sweepAt   int
+}
+
+type trackerKey struct {
+	t reflect.Type
+	p weak.Pointer[byte]
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{snapshots: make(map[trackerKey]map[string]interface{}), sweepAt: minSweep}
+}
+
+func keyOf(ent Entity) (trackerKey, bool) {
+	v := reflect.ValueOf(ent)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return trackerKey{}, false
+	}
+	return trackerKey{t: v.Type(), p: weak.Make((*byte)(v.UnsafePointer()))}, true
+}
+
+func (t *Tracker) Track(ent Entity) {
+	key, ok := keyOf(ent)
+	if !ok {
+		return
+	}
+	snapshot := make(map[string]interface{})
+	for _, f := range columnFields(ent) {
+		snapshot[f.Field.Tag.Get("column")] = snapshotValue(fieldValue(f))
+	}
+	t.mu.Lock()
+	t.snapshots[key] = snapshot
+	if len(t.snapshots) >= t.sweepAt {
+		t.sweep()
+	}
+	t.mu.Unlock()
+}
+
+func (t *Tracker) sweep() {
+	for key := range t.snapshots {
+		if key.p.Value() == nil {
+			delete(t.snapshots, key)
+		}
+	}
+	t.sweepAt = max(2*len(t.snapshots), minSweep)
+}
+
+func (t *Tracker) refresh(ent Entity, columns []string) {
+	key, ok := keyOf(ent)
+	if !ok {
+		return
+	}
+	written := make(map[string]bool)
+	for _, col := range columns {
+		written[strings.ToLower(col)] = true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	snapshot, ok := t.snapshots[key]
+	if !ok {
+		return
+	}
+	for _, f := range columnFields(ent) {
+		column := f.Field.Tag.Get("column")
+		if written[strings.ToLower(column)] {
+			snapshot[column] = snapshotValue(fieldValue(f))
+		}
+	}
+}
+
+func (t *Tracker) snapshot(ent Entity) (map[string]interface{}, bool) {
+	key, ok := keyOf(ent)
+	if !ok {
+		return nil, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	snapshot, ok := t.snapshots[key]
+	if !ok {
+		return nil, false
+	}
+	copied := make(map[string]interface{})
+	for column, v := range snapshot {
+		copied[column] = v
+	}
+	return copied, true
+}
+
+func (t *Tracker) restore(ent Entity, snapshot map[string]interface{}, tracked bool) {
+	key, ok := keyOf(ent)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tracked {
+		t.snapshots[key] = snapshot
+		return
+	}
+	delete(t.snapshots, key)
+}
+
+func (t *Tracker) Forget(ent Entity) {
+	key, ok := keyOf(ent)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	delete(t.snapshots, key)
+	t.mu.Unlock()
+}
+
+func (t *Tracker) Tracked(ent Entity) bool {
+	key, ok := keyOf(ent)
+	if !ok {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok = t.snapshots[key]
+	return ok
+}
+
+func (t *Tracker) Changes(ent Entity) ([]Change, bool) {
+	key, ok := keyOf(ent)
+	if !ok {
+		return nil, false
+	}
+	t.mu.Lock()
+	snapshot, ok := t.snapshots[key]
+	t.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	skip := make(map[string]bool)
+	for _, col := range primaryKeyColumns(ent) {
+		skip[strings.ToLower(col)] = true
+	}
+	if version := versionField(ent); version != nil {
+		skip[strings.ToLower(version.Field.Tag.Get("column"))] = true
+	}
+	changes := make([]Change, 0)
+	for _, f := range columnFields(ent) {
+		column := f.Field.Tag.Get("column")
+		if skip[strings.ToLower(column)] {
+			continue
+		}
+		current := snapshotValue(fieldValue(f))
+		if !reflect.DeepEqual(snapshot[column], current) {
+			changes = append(changes, Change{Column: column, Old: snapshot[column], New: current})
+		}
+	}
+	return changes, true
+}
+
+func snapshotValue(v interface{}) interface{} {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if value, err := valuer.Value(); err == nil {
+			return value
+		}
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil
+	}
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		return snapshotValue(rv.Elem().Interface())
+	}
+	if b, ok := v.([]byte); ok {
+		return append([]byte(nil), b...)
+	}
+	return v
+}
+
+func (c Repository) WithTracking() Repository {
+	if c.tracker == nil {
+		c.tracker = NewTracker()
+	}
+	return c
+}
+
+func (c Repository) WithTracker(t *Tracker) Repository {
+	c.tracker = t
+	return c
+}
+
+func (c Repository) Changes(ent Entity) ([]Change, bool) {
+	if c.tracker == nil {
+		return nil, false
+	}
+	return c.tracker.Changes(ent)
+}
+
+func (c Repository) remember(ent Entity) Entity {
+	if reflect.TypeOf(ent).Kind() != reflect.Ptr {
+		return ent
+	}
+	if c.identity != nil {
+		if existing := c.identity.put(ent); existing != ent {
+			return existing
+		}
+	}
+	if c.tracker != nil {
+		c.tracker.Track(ent)
+	}
+	return ent
+}
+
+func (c Repository) saveChanges(ent Entity, changes []Change) error {
+	if len(changes) > 0 {
+		id, err := ent.GetID()
+		if err != nil {
+			return err
+		}
+		updates := make([]KVP, 0)
+		for _, change := range changes {
+			updates = append(updates, KVP{Key: change.Column, Value: change.New})
+		}
+		if err := c.update(ent, id, updates); err != nil {
+			return err
+		}
+	}
+	return c.saveRelated([]Entity{ent})
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestUpdateKeepsUnwrittenChanges(t *testing.T) {
+	_, db := newFakeDB(t)
+	repo := Repository{DB: db, Clock: fixedClock}.WithTracking()
+	w := &widget{Name: "a"}
+	w.ID = "w1"
+	repo.tracker.Track(w)
+	w.Name = "b"
+	if err := repo.Update(w, w.ID, []KVP{{Key: "deleted_at", Value: nil}}); err != nil {
+		t.Fatal(err)
+	}
+	changes, tracked := repo.Changes(w)
+	if !tracked || len(changes) != 1 || changes[0].Column != "name" {
+		t.Fatalf("expected the unwritten name change to survive, got %+v", changes)
+	}
+}
+
+func TestTrackerDropsCollectedEntities(t *testing.T) {
+	tr := NewTracker()
+	keep := &widget{}
+	tr.Track(keep)
+	for i := 0; i < 200; i++ {
+		tr.Track(&widget{})
+	}
+	runtime.GC()
+	tr.mu.Lock()
+	tr.sweep()
+	n := len(tr.snapshots)
+	tr.mu.Unlock()
+	if n != 1 || !tr.Tracked(keep) {
+		t.Fatalf("expected only the live entity to remain, got %d snapshots", n)
+	}
+	runtime.KeepAlive(keep)
+}
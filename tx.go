@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+type undoLog struct {
+	mu     sync.Mutex
+	states []entityState
+	seen   map[Entity]bool
+}
+
+type entityState struct {
+	ent      Entity
+	value    reflect.Value
+	tracker  *Tracker
+	snapshot map[string]interface{}
+	tracked  bool
+	identity *IdentityMap
+	mapped   bool
+}
+
+func (c Repository) WithContext(ctx context.Context) Repository {
+	c.ctx = ctx
+	return c
+}
+
+func (c Repository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	c.ctx = ctx
+	if c.tx != nil {
+		return fn(c)
+	}
+	return c.retrying(true, func(attempt int) error {
+		c.attempt = attempt
+		return c.withTx(ctx, fn)
+	})
+}
+
+func (c Repository) withTx(ctx context.Context, fn func(Repository) error) (err error) {
+	var tx *sql.Tx
+	err = c.DB.boundary(ctx, OpBegin, c.attempt, func(ctx context.Context) error {
+		var err error
+		tx, err = c.DB.Conn.BeginTx(ctx, nil)
+		return err
+	})
+	if err != nil {
+		if tx != nil {
+			tx.Rollback()
+		}
+		return fmt.Errorf("begin: %w", err)
+	}
+	c.tx = tx
+	written := &writtenTables{}
+	c.written = written
+	c.undo = &undoLog{seen: make(map[Entity]bool)}
+	rollback := func() error {
+		defer c.undo.restore()
+		return c.DB.boundary(ctx, OpRollback, c.attempt, func(context.Context) error {
+			return tx.Rollback()
+		})
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback()
+			panic(p)
+		}
+	}()
+	if err = fn(c); err != nil {
+		if rerr := rollback(); rerr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rerr)
+		}
+		return err
+	}
+	err = c.DB.boundary(ctx, OpCommit, c.attempt, func(context.Context) error {
+		return tx.Commit()
+	})
+	if err != nil {
+		tx.Rollback()
+		c.undo.restore()
+		return &commitError{err}
+	}
+	c.tx = nil
+	c.written = nil
+	c.undo = nil
+	c.invalidate(written.list()...)
+	return nil
+}
+
+func (c Repository) keep(ent Entity) {
+	v := reflect.ValueOf(ent)
+	if c.undo == nil || v.Kind() != reflect.Ptr || v.IsNil() {
+		return
+	}
+	c.undo.mu.Lock()
+	defer c.undo.mu.Unlock()
+	if c.undo.seen[ent] {
+		return
+	}
+	c.undo.seen[ent] = true
+	state := entityState{ent: ent, value: reflect.New(v.Elem().Type()).Elem(), tracker: c.tracker, identity: c.identity}
+	state.value.Set(v.Elem())
+	if c.tracker != nil {
+		state.snapshot, state.tracked = c.tracker.snapshot(ent)
+	}
+	if c.identity != nil {
+		if id, err := ent.GetID(); err == nil {
+			existing, ok := c.identity.Get(ent.GetTable(), id)
+			state.mapped = ok && existing == ent
+		}
+	}
+	c.undo.states = append(c.undo.states, state)
+}
+
+func (u *undoLog) restore() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i := len(u.states) - 1; i >= 0; i-- {
+		state := u.states[i]
+		if state.identity != nil && !state.mapped {
+			if id, err := state.ent.GetID(); err == nil {
+				if existing, ok := state.identity.Get(state.ent.GetTable(), id); ok && existing == state.ent {
+					state.identity.Remove(state.ent)
+				}
+			}
+		}
+		reflect.ValueOf(state.ent).Elem().Set(state.value)
+		if state.tracker != nil {
+			state.tracker.restore(state.ent, state.snapshot, state.tracked)
+		}
+		if state.identity != nil && state.mapped {
+			state.identity.put(state.ent)
+		}
+	}
+	u.states = nil
+	u.seen = make(map[Entity]bool)
+}
+
+func (c Repository) InTx() bool {
+	return c.tx != nil
+}
+
+func (c Repository) context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+func (c Repository) run(st *Statement) error {
+	if c.tx != nil {
+		st.Attempt = c.attempt
+		return c.DB.run(c.context(), c.tx, st)
+	}
+	if st.Operation == OpLoad {
+		return c.DB.run(c.context(), c.DB.Conn, st)
+	}
+	original := *st
+	return c.retrying(st.Query, func(attempt int) error {
+		*st = original
+		st.Attempt = attempt
+		return c.DB.run(c.context(), c.DB.Conn, st)
+	})
+}
+
+func (c Repository) query(st *Statement) (*sql.Rows, error) {
+	st.Query = true
+	err := c.run(st)
+	return st.Rows, err
+}
+
+func (c Repository) exec(st *Statement) (sql.Result, error) {
+	err := c.run(st)
+	return st.Result, err
+}
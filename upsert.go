@@ -0,0 +1,189 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type Upsert struct {
+	Conflict  []string
+	Update    []string
+	DoNothing bool
+	Returning bool
+}
+
+func (c Repository) WithUpsert(u Upsert) Repository {
+	c.upsert = u
+	return c
+}
+
+func primaryKeyColumns(ent Entity) []string {
+	results := make([]string, 0)
+	for _, f := range columnFields(ent) {
+		if tagEnabled(f.Field, "primaryKey") {
+			results = append(results, f.Field.Tag.Get("column"))
+		}
+	}
+	if len(results) == 0 {
+		results = append(results, "id")
+	}
+	return results
+}
+
+func (c Repository) conflictColumns(ent Entity) []string {
+	if len(c.upsert.Conflict) > 0 {
+		return c.upsert.Conflict
+	}
+	return primaryKeyColumns(ent)
+}
+
+func (c Repository) updateColumns(ent Entity, fields []fieldRef) []string {
+	if len(c.upsert.Update) > 0 {
+		return c.upsert.Update
+	}
+	skip := make(map[string]bool)
+	for _, col := range c.conflictColumns(ent) {
+		skip[strings.ToLower(col)] = true
+	}
+	results := make([]string, 0)
+	for _, f := range fields {
+		col := f.Field.Tag.Get("column")
+		if skip[strings.ToLower(col)] || tagEnabled(f.Field, "primaryKey") || tagEnabled(f.Field, "autoCreateTime") || tagEnabled(f.Field, "version") || tagEnabled(f.Field, "softDelete") {
+			continue
+		}
+		results = append(results, col)
+	}
+	return results
+}
+
+func (c Repository) upsertClause(ent Entity, fields []fieldRef) string {
+	table := ent.GetTable()
+	conflict := c.conflictColumns(ent)
+	update := make([]string, 0)
+	if !c.upsert.DoNothing {
+		update = c.updateColumns(ent, fields)
+	}
+	sets := make([]string, 0)
+	relations := relationColumns(fields)
+	if c.DB.GetDialect() == DialectMySQL {
+		for _, col := range update {
+			if relations[strings.ToLower(col)] {
+				sets = append(sets, fmt.Sprintf("%s = COALESCE(VALUES(%s), %s)", col, col, col))
+				continue
+			}
+			sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", col, col))
+		}
+		if version := versionField(ent); version != nil && len(sets) > 0 {
+			col := version.Field.Tag.Get("column")
+			sets = append(sets, fmt.Sprintf("%s = %s + 1", col, col))
+		}
+		if len(sets) == 0 {
+			sets = append(sets, fmt.Sprintf("%s = %s", conflict[0], conflict[0]))
+		}
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	}
+	for _, col := range update {
+		if relations[strings.ToLower(col)] {
+			sets = append(sets, fmt.Sprintf("%s = COALESCE(excluded.%s, %s.%s)", col, col, table, col))
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s = excluded.%s", col, col))
+	}
+	if version := versionField(ent); version != nil && len(sets) > 0 {
+		col := version.Field.Tag.Get("column")
+		sets = append(sets, fmt.Sprintf("%s = %s.%s + 1", col, table, col))
+	}
+	if len(sets) == 0 {
+		return fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(conflict, ", "))
+	}
+	return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflict, ", "), strings.Join(sets, ", "))
+}
+
+func relationColumns(fields []fieldRef) map[string]bool {
+	results := make(map[string]bool)
+	for _, f := range fields {
+		if _, ok := relationTargetType(f.Value.Type()); ok && f.Value.Kind() == reflect.Ptr {
+			results[strings.ToLower(f.Field.Tag.Get("column"))] = true
+		}
+	}
+	return results
+}
+
+func (c Repository) insertRow(ent Entity, action string, q string, fields []fieldRef, auto *fieldRef, returning bool) error {
+	defer c.invalidate(ent.GetTable())
+	values := columnValues(fields)
+	if c.DB.GetDialect() != DialectMySQL {
+		if returning {
+			rows, err := c.query(stmt(ent, action, q+" RETURNING *", values...))
+			if err != nil {
+				return handleSQLError(nil, ent, action, err, "")
+			}
+			defer rows.Close()
+			found := rows.Next()
+			if found {
+				err = ent.ScanLocal(rows, ent)
+			}
+			if err = handleSQLError(rows, ent, action, err, ""); err != nil {
+				return err
+			}
+			if !found {
+				return c.reload(ent)
+			}
+			return nil
+		}
+		if auto != nil && auto.Value.IsZero() && auto.Value.CanAddr() {
+			rows, err := c.query(stmt(ent, action, q+" RETURNING "+auto.Field.Tag.Get("column"), values...))
+			if err != nil {
+				return handleSQLError(nil, ent, action, err, "")
+			}
+			defer rows.Close()
+			if rows.Next() {
+				err = rows.Scan(auto.Value.Addr().Interface())
+			}
+			return handleSQLError(rows, ent, action, err, "")
+		}
+	}
+	res, err := c.exec(stmt(ent, action, q, values...))
+	if err = handleSQLError(nil, ent, action, err, ""); err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 1 {
+		if err = setInsertID(auto, res); err != nil {
+			return err
+		}
+	}
+	if returning {
+		return c.reload(ent)
+	}
+	return nil
+}
+
+func (c Repository) reload(ent Entity) error {
+	fields := columnFields(ent)
+	conflict := c.conflictColumns(ent)
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	for _, col := range conflict {
+		for _, f := range fields {
+			if strings.EqualFold(f.Field.Tag.Get("column"), col) {
+				where = append(where, col+" = ?")
+				args = append(args, redactColumn(ent, col, f.Value.Interface()))
+			}
+		}
+	}
+	if len(where) != len(conflict) {
+		return fmt.Errorf("%s: cannot reload row without values for %s", ent.GetTable(), strings.Join(conflict, ", "))
+	}
+	rows, err := c.query(stmt(ent, OpSelect, "SELECT * FROM "+ent.GetTable()+" WHERE "+strings.Join(where, " AND ")+" LIMIT 1", args...))
+	if err != nil {
+		return handleSQLError(nil, ent, "SELECT", err, "")
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return handleSQLError(rows, ent, "SELECT", sql.ErrNoRows, "")
+	}
+	err = ent.ScanLocal(rows, ent)
+	return handleSQLError(rows, ent, "SELECT", err, "")
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSaveUpsertsPerDialect(t *testing.T) {
+	for dialect, want := range map[string]string{
+		DialectMySQL:    "ON DUPLICATE KEY UPDATE updated = VALUES(updated), name = VALUES(name), version = version + 1",
+		DialectPostgres: "ON CONFLICT (id) DO UPDATE SET updated = excluded.updated, name = excluded.name, version = widget.version + 1",
+		DialectSQLite:   "ON CONFLICT (id) DO UPDATE SET updated = excluded.updated, name = excluded.name, version = widget.version + 1",
+	} {
+		d, db := newFakeDB(t)
+		db.SetDialect(dialect)
+		if err := (Repository{DB: db}).Save(&widget{Name: "a"}); err != nil {
+			t.Fatal(err)
+		}
+		if got := d.statements()[0]; !strings.HasPrefix(got, "INSERT INTO widget (id,created,updated,deleted_at,name,version) VALUES") || !strings.Contains(got, want) {
+			t.Fatalf("%s: %s", dialect, got)
+		}
+	}
+}
+
+func TestSaveUpsertOptions(t *testing.T) {
+	d, db := newFakeDB(t)
+	db.SetDialect(DialectPostgres)
+	r := Repository{DB: db}
+	if err := r.WithUpsert(Upsert{Conflict: []string{"name"}, DoNothing: true}).Save(&widget{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.statements()[0]; !strings.Contains(got, "ON CONFLICT (name) DO NOTHING") {
+		t.Fatalf("do nothing: %s", got)
+	}
+	d.reset()
+	d.returns("RETURNING *", widgetColumns, widgetRow("w9", "stored", 7))
+	w := &widget{Name: "a"}
+	if err := r.WithUpsert(Upsert{Update: []string{"name"}, Returning: true}).Save(w); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.statements()[0]; !strings.Contains(got, "DO UPDATE SET name = excluded.name, version = widget.version + 1 RETURNING *") {
+		t.Fatalf("returning: %s", got)
+	}
+	if w.ID != "w9" || w.Name != "stored" || w.Version != 7 {
+		t.Fatalf("returned row not scanned: %+v", w)
+	}
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+func versionField(ent Entity) *fieldRef {
+	for _, f := range columnFields(ent) {
+		if tagEnabled(f.Field, "version") {
+			return &f
+		}
+	}
+	return nil
+}
+
+func versionValue(f *fieldRef) (int64, error) {
+	switch f.Value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.Value.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(f.Value.Uint()), nil
+	}
+	return 0, fmt.Errorf("version column %s must be an integer", f.Field.Name)
+}
+
+func setVersion(f *fieldRef, v int64) {
+	if !f.Value.CanSet() {
+		return
+	}
+	switch f.Value.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f.Value.SetUint(uint64(v))
+	default:
+		f.Value.SetInt(v)
+	}
+}
+
+func (c Repository) exists(e Entity, id string) (bool, error) {
+	rows, err := c.query(stmt(e, OpSelect, "SELECT 1 FROM "+e.GetTable()+" WHERE ID = ? LIMIT 1", id))
+	if err != nil {
+		return false, handleSQLError(nil, e, "SELECT", err, id)
+	}
+	defer rows.Close()
+	found := rows.Next()
+	return found, handleSQLError(rows, e, "SELECT", nil, id)
+}
+
+func withoutColumn(updates []KVP, column string) []KVP {
+	results := make([]KVP, 0)
+	for _, u := range updates {
+		if !strings.EqualFold(u.Key, column) {
+			results = append(results, u)
+		}
+	}
+	return results
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUpdateChecksAndIncrementsVersion(t *testing.T) {
+	d, db := newFakeDB(t)
+	w := &widget{Name: "a", Version: 3}
+	w.ID = "w1"
+	if err := (Repository{DB: db}).Update(w, "w1", []KVP{{Key: "name", Value: "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if w.Version != 4 {
+		t.Fatalf("version = %d", w.Version)
+	}
+	got := d.statements()[0]
+	if !strings.Contains(got, "version = version + 1 WHERE ID = ? AND version = ?") || !strings.HasSuffix(got, ",w1,3") {
+		t.Fatalf("update ran %s", got)
+	}
+}
+
+func TestUpdateTellsStaleFromMissing(t *testing.T) {
+	d, db := newFakeDB(t)
+	d.affected = 0
+	r := Repository{DB: db}
+	w := &widget{Name: "a", Version: 3}
+	d.returns("SELECT 1 FROM widget", []string{"1"}, []driver.Value{int64(1)})
+	err := r.Update(w, "w1", []KVP{{Key: "name", Value: "b"}})
+	if !errors.Is(err, ErrStaleEntity) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("existing row: %v", err)
+	}
+	if w.Version != 3 {
+		t.Fatalf("stale update changed the version to %d", w.Version)
+	}
+	d.returns("SELECT 1 FROM widget", []string{"1"})
+	err = r.Update(w, "w1", []KVP{{Key: "name", Value: "b"}})
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrStaleEntity) {
+		t.Fatalf("missing row: %v", err)
+	}
+}